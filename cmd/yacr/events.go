@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/willdurand/containers/internal/cli"
+	"github.com/willdurand/containers/internal/yacr"
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "events <id>",
+		Short: "Display the events and resource usage statistics of a container",
+		Run:   cli.HandleErrors(events),
+		Args:  cobra.ExactArgs(1),
+	}
+	cmd.Flags().Bool("stats", false, "display the statistics only once")
+	cmd.Flags().Duration("interval", 5*time.Second, "set the statistics collection interval")
+	rootCmd.AddCommand(cmd)
+}
+
+func events(cmd *cobra.Command, args []string) error {
+	rootDir, _ := cmd.Flags().GetString("root")
+	stats, _ := cmd.Flags().GetBool("stats")
+	interval, _ := cmd.Flags().GetDuration("interval")
+
+	opts := yacr.EventsOpts{
+		ID:       args[0],
+		Stats:    stats,
+		Interval: interval,
+	}
+
+	if err := yacr.Events(rootDir, opts, os.Stdout); err != nil {
+		return fmt.Errorf("events: %w", err)
+	}
+
+	return nil
+}
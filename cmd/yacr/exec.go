@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/willdurand/containers/internal/cli"
+	"github.com/willdurand/containers/internal/yacr"
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "exec <id> [command...]",
+		Short: "Execute a new process inside a container",
+		Run:   cli.HandleErrors(execute),
+		Args:  cobra.MinimumNArgs(1),
+	}
+	// Stop parsing the flags after the container ID so that the flags of the
+	// command are not interpreted by yacr.
+	cmd.Flags().SetInterspersed(false)
+	cmd.Flags().StringP("process", "p", "", "path to a process.json file")
+	cmd.Flags().BoolP("tty", "t", false, "allocate a pseudo-TTY")
+	cmd.Flags().String("console-socket", "", "console unix socket used to pass a PTY descriptor")
+	cmd.Flags().String("pid-file", "", "specify the file to write the process id to")
+	cmd.Flags().BoolP("detach", "d", false, "detach from the process")
+	cmd.Flags().String("cwd", "", "current working directory in the container")
+	cmd.Flags().StringArrayP("env", "e", []string{}, "set environment variables")
+	rootCmd.AddCommand(cmd)
+
+	processCmd := &cobra.Command{
+		Use:    "process",
+		Run:    cli.HandleErrors(execProcess),
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		// This command runs in the container, where the root directory does not
+		// exist and should not be created.
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	}
+	cmd.AddCommand(processCmd)
+}
+
+func execute(cmd *cobra.Command, args []string) error {
+	rootDir, _ := cmd.Flags().GetString("root")
+	processPath, _ := cmd.Flags().GetString("process")
+	tty, _ := cmd.Flags().GetBool("tty")
+	consoleSocket, _ := cmd.Flags().GetString("console-socket")
+	pidFile, _ := cmd.Flags().GetString("pid-file")
+	detach, _ := cmd.Flags().GetBool("detach")
+	cwd, _ := cmd.Flags().GetString("cwd")
+	env, _ := cmd.Flags().GetStringArray("env")
+
+	opts := yacr.ExecOpts{
+		ID:            args[0],
+		ProcessPath:   processPath,
+		Args:          args[1:],
+		Env:           env,
+		Cwd:           cwd,
+		Tty:           tty,
+		ConsoleSocket: consoleSocket,
+		PidFile:       pidFile,
+		Detach:        detach,
+	}
+
+	exitStatus, err := yacr.Exec(rootDir, opts)
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	if !detach {
+		os.Exit(exitStatus)
+	}
+
+	return nil
+}
+
+func execProcess(cmd *cobra.Command, args []string) error {
+	if err := yacr.ExecProcess(); err != nil {
+		return fmt.Errorf("exec process: %w", err)
+	}
+
+	return nil
+}
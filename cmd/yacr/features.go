@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/willdurand/containers/internal/cli"
+	"github.com/willdurand/containers/internal/yacr"
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "features",
+		Short: "Show the features supported by the runtime",
+		Run:   cli.HandleErrors(features),
+		Args:  cobra.NoArgs,
+	}
+	rootCmd.AddCommand(cmd)
+}
+
+func features(cmd *cobra.Command, args []string) error {
+	return yacr.PrintFeatures(os.Stdout)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/willdurand/containers/internal/cli"
+	"github.com/willdurand/containers/internal/yacr"
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "pause <id>",
+		Short: "Pause all the processes of a container",
+		Run:   cli.HandleErrors(pause),
+		Args:  cobra.ExactArgs(1),
+	}
+	rootCmd.AddCommand(cmd)
+}
+
+func pause(cmd *cobra.Command, args []string) error {
+	rootDir, _ := cmd.Flags().GetString("root")
+
+	if err := yacr.Pause(rootDir, args[0]); err != nil {
+		return fmt.Errorf("pause: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/willdurand/containers/internal/cli"
+	"github.com/willdurand/containers/internal/yacr"
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "ps <id>",
+		Short: "List the processes running inside a container",
+		Run:   cli.HandleErrors(ps),
+		Args:  cobra.ExactArgs(1),
+	}
+	cmd.Flags().StringP("format", "f", "table", `select one of: "table" or "json" (host PIDs only)`)
+	rootCmd.AddCommand(cmd)
+}
+
+func ps(cmd *cobra.Command, args []string) error {
+	rootDir, _ := cmd.Flags().GetString("root")
+	format, _ := cmd.Flags().GetString("format")
+
+	processes, err := yacr.Ps(rootDir, args[0])
+	if err != nil {
+		return fmt.Errorf("ps: %w", err)
+	}
+
+	switch format {
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 8, 1, 3, ' ', 0)
+		fmt.Fprint(w, "PID\tNSPID\tUSER\tCOMMAND\n")
+
+		for _, process := range processes {
+			fmt.Fprintf(
+				w, "%d\t%d\t%s\t%s\n",
+				process.PID,
+				process.NSPID,
+				process.User,
+				process.Command,
+			)
+		}
+
+		return w.Flush()
+
+	case "json":
+		// Like runc, we deliberately output the list of host PIDs only because
+		// containerd parses the output of `ps --format json` as a list of
+		// integers. The namespaced PIDs, users and commands are only part of the
+		// "table" format.
+		pids := make([]int, 0, len(processes))
+		for _, process := range processes {
+			pids = append(pids, process.PID)
+		}
+
+		return json.NewEncoder(os.Stdout).Encode(pids)
+
+	default:
+		return fmt.Errorf("ps: invalid format '%s'", format)
+	}
+}
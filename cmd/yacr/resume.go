@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/willdurand/containers/internal/cli"
+	"github.com/willdurand/containers/internal/yacr"
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "resume <id>",
+		Short: "Resume all the processes of a paused container",
+		Run:   cli.HandleErrors(resume),
+		Args:  cobra.ExactArgs(1),
+	}
+	rootCmd.AddCommand(cmd)
+}
+
+func resume(cmd *cobra.Command, args []string) error {
+	rootDir, _ := cmd.Flags().GetString("root")
+
+	if err := yacr.Resume(rootDir, args[0]); err != nil {
+		return fmt.Errorf("resume: %w", err)
+	}
+
+	return nil
+}
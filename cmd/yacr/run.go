@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/willdurand/containers/internal/cli"
+	"github.com/willdurand/containers/internal/yacr"
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "run <id>",
+		Short: "Create and start a container, then wait for it",
+		Run:   cli.HandleErrors(run),
+		Args:  cobra.ExactArgs(1),
+	}
+	cmd.Flags().StringP("bundle", "b", "", "path to the root of the bundle directory")
+	cmd.MarkFlagRequired("bundle")
+	cmd.Flags().String("pid-file", "", "specify the file to write the process id to")
+	cmd.Flags().String("console-socket", "", "console unix socket used to pass a PTY descriptor")
+	cmd.Flags().Bool("no-pivot", false, "do not use pivot root to jail process inside rootfs")
+	cmd.Flags().Bool("keep", false, "do not delete the container after it exits")
+	rootCmd.AddCommand(cmd)
+}
+
+func run(cmd *cobra.Command, args []string) error {
+	rootDir, _ := cmd.Flags().GetString("root")
+	bundle, _ := cmd.Flags().GetString("bundle")
+	pidFile, _ := cmd.Flags().GetString("pid-file")
+	consoleSocket, _ := cmd.Flags().GetString("console-socket")
+	noPivot, _ := cmd.Flags().GetBool("no-pivot")
+	keep, _ := cmd.Flags().GetBool("keep")
+	logFile, _ := cmd.Flags().GetString("log")
+	logFormat, _ := cmd.Flags().GetString("log-format")
+	debug, _ := cmd.Flags().GetBool("debug")
+
+	opts := yacr.RunOpts{
+		CreateOpts: yacr.CreateOpts{
+			ID:            args[0],
+			Bundle:        bundle,
+			PidFile:       pidFile,
+			ConsoleSocket: consoleSocket,
+			NoPivot:       noPivot,
+			LogFile:       logFile,
+			LogFormat:     logFormat,
+			Debug:         debug,
+		},
+		Keep: keep,
+	}
+
+	exitStatus, err := yacr.Run(rootDir, opts)
+	if err != nil {
+		return fmt.Errorf("run: %w", err)
+	}
+
+	os.Exit(exitStatus)
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/spf13/cobra"
+	"github.com/willdurand/containers/internal/cli"
+	"github.com/willdurand/containers/internal/yacr"
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "update <id>",
+		Short: "Update the resource limits of a container",
+		Run:   cli.HandleErrors(update),
+		Args:  cobra.ExactArgs(1),
+	}
+	cmd.Flags().StringP("resources", "r", "", "path to a JSON file containing the resources to update ('-' for stdin)")
+	cmd.Flags().Int64("memory", 0, "memory limit in bytes (-1 for unlimited)")
+	cmd.Flags().Int64("memory-reservation", 0, "memory soft limit in bytes")
+	cmd.Flags().Int64("memory-swap", 0, "total memory (memory + swap) limit in bytes (-1 for unlimited)")
+	cmd.Flags().Uint64("cpu-shares", 0, "CPU shares (relative weight)")
+	cmd.Flags().Int64("cpu-quota", 0, "CPU time in microseconds for each period (-1 for unlimited)")
+	cmd.Flags().Uint64("cpu-period", 0, "CPU period in microseconds")
+	cmd.Flags().String("cpuset-cpus", "", "CPUs in which to allow execution (e.g., 0-3)")
+	cmd.Flags().String("cpuset-mems", "", "memory nodes in which to allow execution (e.g., 0-1)")
+	cmd.Flags().Int64("pids-limit", 0, "maximum number of processes (-1 for unlimited)")
+	cmd.Flags().Uint16("blkio-weight", 0, "block I/O weight (10-1000)")
+	rootCmd.AddCommand(cmd)
+}
+
+func update(cmd *cobra.Command, args []string) error {
+	rootDir, _ := cmd.Flags().GetString("root")
+	resourcesFile, _ := cmd.Flags().GetString("resources")
+
+	// Only the flags that have been set are used so that the other resources
+	// are left unchanged.
+	var resources runtimespec.LinuxResources
+	flags := cmd.Flags()
+
+	memory := func() *runtimespec.LinuxMemory {
+		if resources.Memory == nil {
+			resources.Memory = new(runtimespec.LinuxMemory)
+		}
+		return resources.Memory
+	}
+	if flags.Changed("memory") {
+		v, _ := flags.GetInt64("memory")
+		memory().Limit = &v
+	}
+	if flags.Changed("memory-reservation") {
+		v, _ := flags.GetInt64("memory-reservation")
+		memory().Reservation = &v
+	}
+	if flags.Changed("memory-swap") {
+		v, _ := flags.GetInt64("memory-swap")
+		memory().Swap = &v
+	}
+
+	cpu := func() *runtimespec.LinuxCPU {
+		if resources.CPU == nil {
+			resources.CPU = new(runtimespec.LinuxCPU)
+		}
+		return resources.CPU
+	}
+	if flags.Changed("cpu-shares") {
+		v, _ := flags.GetUint64("cpu-shares")
+		cpu().Shares = &v
+	}
+	if flags.Changed("cpu-quota") {
+		v, _ := flags.GetInt64("cpu-quota")
+		cpu().Quota = &v
+	}
+	if flags.Changed("cpu-period") {
+		v, _ := flags.GetUint64("cpu-period")
+		cpu().Period = &v
+	}
+	if flags.Changed("cpuset-cpus") {
+		cpu().Cpus, _ = flags.GetString("cpuset-cpus")
+	}
+	if flags.Changed("cpuset-mems") {
+		cpu().Mems, _ = flags.GetString("cpuset-mems")
+	}
+
+	if flags.Changed("pids-limit") {
+		v, _ := flags.GetInt64("pids-limit")
+		resources.Pids = &runtimespec.LinuxPids{Limit: v}
+	}
+
+	if flags.Changed("blkio-weight") {
+		v, _ := flags.GetUint16("blkio-weight")
+		resources.BlockIO = &runtimespec.LinuxBlockIO{Weight: &v}
+	}
+
+	opts := yacr.UpdateOpts{
+		ID:            args[0],
+		ResourcesFile: resourcesFile,
+		Resources:     resources,
+	}
+
+	if err := yacr.Update(rootDir, opts); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	return nil
+}
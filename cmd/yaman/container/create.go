@@ -31,6 +31,7 @@ func addCreateFlagsToCommand(cmd *cobra.Command) {
 	cmd.Flags().BoolP("interactive", "i", false, "keep stdin open")
 	cmd.Flags().BoolP("publish-all", "P", false, "publish all exposed ports to random ports")
 	cmd.Flags().String("pull", string(registry.PullMissing), `pull image before running ("always"|"missing"|"never")`)
+	cmd.Flags().Bool("read-only", false, "mount the container's root filesystem as read-only")
 	cmd.Flags().Bool("rm", false, "automatically remove the container when it exits")
 	cmd.Flags().String("runtime", "", "runtime to use for this container")
 	cmd.Flags().BoolP("tty", "t", false, "allocate a pseudo-tty")
@@ -49,6 +50,7 @@ func makeContainerOptsFromCommand(cmd *cobra.Command, command []string) containe
 	hostname, _ := cmd.Flags().GetString("hostname")
 	interactive, _ := cmd.Flags().GetBool("interactive")
 	publishAll, _ := cmd.Flags().GetBool("publish-all")
+	readOnly, _ := cmd.Flags().GetBool("read-only")
 	rm, _ := cmd.Flags().GetBool("rm")
 	tty, _ := cmd.Flags().GetBool("tty")
 
@@ -61,6 +63,7 @@ func makeContainerOptsFromCommand(cmd *cobra.Command, command []string) containe
 		Tty:         tty,
 		Detach:      false,
 		PublishAll:  publishAll,
+		ReadOnly:    readOnly,
 	}
 }
 
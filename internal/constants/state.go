@@ -1,13 +1,18 @@
 package constants
 
+import runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+
 const (
 	// StateCreating indicates that the container is being created.
-	StateCreating string = "creating"
+	StateCreating runtimespec.ContainerState = runtimespec.StateCreating
 	// StateCreated indicates that the runtime has finished the create operation.
-	StateCreated string = "created"
+	StateCreated runtimespec.ContainerState = runtimespec.StateCreated
 	// StateRunning indicates that the container process has executed the
 	// user-specified program but has not exited.
-	StateRunning string = "running"
+	StateRunning runtimespec.ContainerState = runtimespec.StateRunning
+	// StatePaused indicates that the container process has been frozen. This
+	// state is not defined in the runtime-spec but it is used by runc.
+	StatePaused runtimespec.ContainerState = "paused"
 	// StateStopped indicates that the container process has exited.
-	StateStopped string = "stopped"
+	StateStopped runtimespec.ContainerState = runtimespec.StateStopped
 )
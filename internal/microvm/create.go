@@ -35,6 +35,12 @@ func Create(rootDir, containerId, bundle string, opts CreateOpts) error {
 		return err
 	}
 
+	// The root filesystem is shared with the VM by `virtiofsd`, which cannot
+	// make it read-only (see `Features()`).
+	if container.Spec.Root != nil && container.Spec.Root.Readonly {
+		return errors.New("read-only root filesystems are not supported")
+	}
+
 	if opts.PidFile == "" {
 		opts.PidFile = filepath.Join(container.BaseDir, "container.pid")
 	}
@@ -0,0 +1,52 @@
+package microvm
+
+import (
+	"encoding/json"
+	"io"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/opencontainers/runtime-spec/specs-go/features"
+	"github.com/willdurand/containers/internal/runtime"
+)
+
+// Features returns the features supported by microvm. Only the process (args
+// and env) and the hostname of the runtime configuration are passed to the VM.
+//
+// The mount options, namespaces and capabilities are not listed because the
+// container is isolated by the VM, in which they do not apply. The hooks are
+// not executed either but an empty list cannot be expressed in the features
+// document.
+//
+// See: https://github.com/opencontainers/runtime-spec/blob/main/features.md
+func Features() *features.Features {
+	disabled := false
+
+	return &features.Features{
+		OCIVersionMin: "1.0.0",
+		OCIVersionMax: runtimespec.Version,
+		Linux: &features.Linux{
+			Cgroup: &features.Cgroup{
+				V1:          &disabled,
+				V2:          &disabled,
+				Systemd:     &disabled,
+				SystemdUser: &disabled,
+				Rdma:        &disabled,
+			},
+			Seccomp:  &features.Seccomp{Enabled: &disabled},
+			Apparmor: &features.Apparmor{Enabled: &disabled},
+			Selinux:  &features.Selinux{Enabled: &disabled},
+			IntelRdt: &features.IntelRdt{Enabled: &disabled},
+		},
+		Annotations: map[string]string{
+			runtime.ReadonlyRootfsAnnotation: "false",
+		},
+	}
+}
+
+// PrintFeatures writes the features document to the given writer.
+func PrintFeatures(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(Features())
+}
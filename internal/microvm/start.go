@@ -17,5 +17,5 @@ func Start(rootDir, containerId string) error {
 		return fmt.Errorf("start: unexpected status '%s' for container '%s'", container.State.Status, container.ID())
 	}
 
-	return container.UpdateStatus(constants.StateRunning)
+	return container.UpdateStatus(constants.StateCreated, constants.StateRunning)
 }
@@ -13,6 +13,12 @@ import (
 
 	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/willdurand/containers/internal/constants"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	stateFileName = "state.json"
+	lockFileName  = "state.lock"
 )
 
 type BaseContainer struct {
@@ -55,7 +61,7 @@ func New(rootDir string, id string, bundleDir string) (*BaseContainer, error) {
 		},
 		CreatedAt:     time.Now(),
 		BaseDir:       containerDir,
-		StateFilePath: filepath.Join(containerDir, "state.json"),
+		StateFilePath: filepath.Join(containerDir, stateFileName),
 	}, nil
 }
 
@@ -111,6 +117,10 @@ func (c *BaseContainer) IsRunning() bool {
 	return c.State.Status == constants.StateRunning
 }
 
+func (c *BaseContainer) IsPaused() bool {
+	return c.State.Status == constants.StatePaused
+}
+
 func (c *BaseContainer) IsStopped() bool {
 	return c.State.Status == constants.StateStopped
 }
@@ -123,32 +133,60 @@ func (c *BaseContainer) Rootfs() string {
 	return rootfs
 }
 
-func (c *BaseContainer) UpdateStatus(newStatus string) error {
-	c.State.Status = newStatus
-	return c.Save()
+// UpdateStatus changes the status of the container from `from` to `to` and
+// saves its state. The state is reloaded while holding the lock so that the
+// change is based on the latest state. It fails when the container does not
+// have the expected status or when it has been deleted in the meantime.
+func (c *BaseContainer) UpdateStatus(from, to runtimespec.ContainerState) error {
+	return c.updateState(from, func(state *runtimespec.State) {
+		state.Status = to
+	})
 }
 
+// Save creates the container directory if needed and saves the state of the
+// container.
 func (c *BaseContainer) Save() error {
 	if err := os.MkdirAll(c.BaseDir, 0o755); err != nil {
 		return fmt.Errorf("failed to create container directory: %w", err)
 	}
 
-	if err := c.saveContainerState(); err != nil {
+	unlock, err := c.Lock()
+	if err != nil {
 		return err
 	}
+	defer unlock()
 
-	return nil
+	return c.saveContainerState()
 }
 
 func (c *BaseContainer) SetPid(pid int) {
 	c.State.Pid = pid
 }
 
+// SaveAsCreated saves the PID of the container process (see `SetPid()`) and
+// changes the status of the container from "creating" to "created".
 func (c *BaseContainer) SaveAsCreated() error {
-	return c.UpdateStatus(constants.StateCreated)
+	pid := c.State.Pid
+
+	return c.updateState(constants.StateCreating, func(state *runtimespec.State) {
+		state.Pid = pid
+		state.Status = constants.StateCreated
+	})
 }
 
 func (c *BaseContainer) Destroy() error {
+	// Holding the lock ensures that no other process is writing the state while
+	// the container directory is removed. Once removed, the state cannot be
+	// written anymore.
+	unlock, err := c.Lock()
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer unlock()
+
 	if err := os.RemoveAll(c.BaseDir); err != nil {
 		return err
 	}
@@ -156,6 +194,56 @@ func (c *BaseContainer) Destroy() error {
 	return nil
 }
 
+// Lock acquires an exclusive lock on the state of the container and returns a
+// function to release it. All the changes to the state file (and the other
+// files of the container directory) must be made while holding this lock. The
+// lock is held on a separate file because the state file is replaced on each
+// write.
+func (c *BaseContainer) Lock() (func(), error) {
+	f, err := os.OpenFile(filepath.Join(c.BaseDir, lockFileName), os.O_RDONLY|os.O_CREATE|unix.O_CLOEXEC, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state lock: %w", err)
+	}
+
+	for {
+		err = unix.Flock(int(f.Fd()), unix.LOCK_EX)
+		if !errors.Is(err, unix.EINTR) {
+			break
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock state: %w", err)
+	}
+
+	return func() {
+		unix.Flock(int(f.Fd()), unix.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// updateState reloads the state while holding the lock, checks that the
+// container has the expected status, applies a change and saves the state.
+func (c *BaseContainer) updateState(from runtimespec.ContainerState, update func(state *runtimespec.State)) error {
+	unlock, err := c.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := c.loadContainerState(); err != nil {
+		return err
+	}
+
+	if c.State.Status != from {
+		return fmt.Errorf("unexpected status '%s' for container '%s'", c.State.Status, c.ID())
+	}
+
+	update(&c.State)
+
+	return c.saveContainerState()
+}
+
 func (c *BaseContainer) loadContainerState() error {
 	data, err := ioutil.ReadFile(c.StateFilePath)
 	if err != nil {
@@ -169,18 +257,48 @@ func (c *BaseContainer) loadContainerState() error {
 	return nil
 }
 
+// ReloadState reads the state of the container again, e.g. once `Lock()` has
+// been called to make sure that the state has not been changed by another
+// process in the meantime. The caller must hold the lock.
+func (c *BaseContainer) ReloadState() error {
+	if err := c.loadContainerState(); err != nil {
+		return err
+	}
+
+	if c.processExited() {
+		c.State.Status = constants.StateStopped
+		return c.saveContainerState()
+	}
+
+	return nil
+}
+
 func (c *BaseContainer) refreshContainerState() error {
+	if c.processExited() {
+		unlock, err := c.Lock()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		// The state might have been changed by another process since we loaded
+		// it so we check it again while holding the lock.
+		return c.ReloadState()
+	}
+
+	return nil
+}
+
+// processExited returns `true` when the container process is no longer
+// running and the container is not stopped yet.
+func (c *BaseContainer) processExited() bool {
 	if c.State.Pid == 0 || c.IsStopped() {
-		return nil
+		return false
 	}
 
 	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", c.State.Pid))
 	// One character from the string "RSDZTW" where R is running, S is sleeping in an interruptible wait, D is waiting in uninterruptible disk sleep, Z is zombie, T is traced or stopped (on a signal), and W is paging.
-	if err != nil || bytes.SplitN(data, []byte{' '}, 3)[2][0] == 'Z' {
-		return c.UpdateStatus(constants.StateStopped)
-	}
-
-	return nil
+	return err != nil || bytes.SplitN(data, []byte{' '}, 3)[2][0] == 'Z'
 }
 
 func (c *BaseContainer) saveContainerState() error {
@@ -189,9 +307,35 @@ func (c *BaseContainer) saveContainerState() error {
 		return fmt.Errorf("failed to serialize container state: %w", err)
 	}
 
-	if err := ioutil.WriteFile(c.StateFilePath, data, 0o644); err != nil {
+	if err := WriteFileAtomic(c.StateFilePath, data, 0o644); err != nil {
 		return fmt.Errorf("failed to save container state: %w", err)
 	}
 
 	return nil
 }
+
+// WriteFileAtomic writes data to a temporary file, which then replaces the
+// named file, so that readers never see a partially written file.
+func WriteFileAtomic(name string, data []byte, perm fs.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+
+	return err
+}
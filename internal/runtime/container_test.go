@@ -0,0 +1,170 @@
+package runtime
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/willdurand/containers/internal/constants"
+)
+
+func TestSaveIsAtomic(t *testing.T) {
+	rootDir := t.TempDir()
+
+	container, err := New(rootDir, "test-id", "")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := container.Save(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+
+		go func(i int) {
+			defer wg.Done()
+
+			c, err := New(rootDir, "test-id", "")
+			if err != nil {
+				t.Errorf("expected no error, got: %v", err)
+				return
+			}
+			c.State.Annotations = map[string]string{"i": string(rune('a' + i))}
+			if err := c.Save(); err != nil {
+				t.Errorf("expected no error, got: %v", err)
+			}
+		}(i)
+
+		go func() {
+			defer wg.Done()
+
+			data, err := os.ReadFile(filepath.Join(rootDir, "test-id", "state.json"))
+			if err != nil {
+				t.Errorf("expected no error, got: %v", err)
+				return
+			}
+
+			var state runtimespec.State
+			if err := json.Unmarshal(data, &state); err != nil {
+				t.Errorf("expected a valid state, got: %s", data)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, err := os.ReadDir(filepath.Join(rootDir, "test-id"))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != "state.json" && entry.Name() != "state.lock" {
+			t.Errorf("unexpected file: %s", entry.Name())
+		}
+	}
+}
+
+func TestUpdateStatusAfterDestroy(t *testing.T) {
+	rootDir := t.TempDir()
+
+	container, err := New(rootDir, "test-id", "")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := container.Save(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if err := container.Destroy(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if err := container.UpdateStatus(constants.StateCreating, constants.StateStopped); err == nil {
+		t.Errorf("expected an error")
+	}
+
+	if _, err := os.Stat(container.BaseDir); !os.IsNotExist(err) {
+		t.Errorf("expected container directory to not exist, got: %v", err)
+	}
+}
+
+func TestUpdateStatus(t *testing.T) {
+	rootDir := t.TempDir()
+
+	container, err := New(rootDir, "test-id", "")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := container.Save(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// Another process creates the container while `container` still has the
+	// initial state.
+	other, err := Load(rootDir, "test-id")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	other.SetPid(123)
+	if err := other.SaveAsCreated(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if err := container.UpdateStatus(constants.StateCreating, constants.StateCreated); err == nil {
+		t.Errorf("expected an error")
+	}
+
+	if err := container.UpdateStatus(constants.StateCreated, constants.StateRunning); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	data, err := os.ReadFile(container.StateFilePath)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	var state runtimespec.State
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if state.Status != constants.StateRunning {
+		t.Errorf("expected status: %s, got: %s", constants.StateRunning, state.Status)
+	}
+	if state.Pid != 123 {
+		t.Errorf("expected pid: 123, got: %d", state.Pid)
+	}
+}
+
+func TestReloadState(t *testing.T) {
+	rootDir := t.TempDir()
+
+	container, err := New(rootDir, "test-id", "")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	container.SetPid(os.Getpid())
+	if err := container.Save(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	other, err := Load(rootDir, "test-id")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := other.UpdateStatus(constants.StateCreating, constants.StateStopped); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if err := container.ReloadState(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if !container.IsStopped() {
+		t.Errorf("expected container to be stopped, got: %s", container.State.Status)
+	}
+}
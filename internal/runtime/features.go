@@ -0,0 +1,204 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/opencontainers/runtime-spec/specs-go/features"
+	"github.com/sirupsen/logrus"
+)
+
+// runtimeMountOptions contains the mount options that are interpreted by the
+// OCI runtimes. Other options are passed to the filesystems so we cannot tell
+// whether they are supported or not.
+var runtimeMountOptions = []string{
+	"async", "atime", "bind", "defaults", "dev", "diratime", "dirsync", "exec",
+	"idmap", "mand", "noatime", "nodev", "nodiratime", "noexec", "nomand",
+	"norelatime", "nostrictatime", "nosuid", "nosymfollow", "private", "ratime",
+	"rbind", "rdev", "rdiratime", "relatime", "remount", "rexec", "ridmap",
+	"rnoatime", "rnodev", "rnodiratime", "rnoexec", "rnorelatime",
+	"rnostrictatime", "rnosuid", "rnosymfollow", "ro", "rprivate", "rrelatime",
+	"rro", "rrw", "rshared", "rslave", "rstrictatime", "rsuid", "rsymfollow",
+	"runbindable", "rw", "shared", "slave", "strictatime", "suid", "symfollow",
+	"sync", "tmpcopyup", "unbindable",
+}
+
+// ReadonlyRootfsAnnotation is the annotation of the features document that
+// tells whether a runtime supports read-only root filesystems ("true" or
+// "false"). There is no dedicated field in the features document for this.
+const ReadonlyRootfsAnnotation = "io.github.willdurand.containers.root.readonly"
+
+// unknownCommand matches the error messages printed by the runtimes that do
+// not implement the `features` command (cobra-based runtimes and older runc
+// versions).
+var unknownCommand = regexp.MustCompile(`(?i)unknown command|no help topic`)
+
+// QueryFeatures calls `<runtime> features` and returns the features supported
+// by an OCI runtime. It returns `nil` when the runtime does not implement this
+// command, and an error when the command fails for another reason.
+//
+// See: https://github.com/opencontainers/runtime-spec/blob/main/features.md
+func QueryFeatures(runtimePath string) (*features.Features, error) {
+	output, err := exec.Command(runtimePath, "features").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("failed to query runtime features: %w", err)
+		}
+
+		if unknownCommand.Match(exitErr.Stderr) {
+			logrus.WithField("runtime", runtimePath).Debug("runtime does not implement the features command")
+			return nil, nil
+		}
+
+		if msg := bytes.TrimSpace(exitErr.Stderr); len(msg) > 0 {
+			return nil, fmt.Errorf("failed to query runtime features: %w (stderr: %s)", err, msg)
+		}
+
+		return nil, fmt.Errorf("failed to query runtime features: %w", err)
+	}
+
+	f := new(features.Features)
+	if err := json.Unmarshal(output, f); err != nil {
+		return nil, fmt.Errorf("failed to parse runtime features: %w", err)
+	}
+
+	return f, nil
+}
+
+// CheckFeatures returns an error when a runtime configuration uses a feature
+// that is not supported by an OCI runtime. The features that are not listed
+// in the features document are not checked.
+func CheckFeatures(spec *runtimespec.Spec, f *features.Features) error {
+	if f == nil {
+		return nil
+	}
+
+	if f.Hooks != nil && spec.Hooks != nil {
+		data, err := json.Marshal(spec.Hooks)
+		if err != nil {
+			return err
+		}
+
+		var hooks map[string][]runtimespec.Hook
+		if err := json.Unmarshal(data, &hooks); err != nil {
+			return err
+		}
+
+		for name := range hooks {
+			if !contains(f.Hooks, name) {
+				return fmt.Errorf("runtime does not support '%s' hooks", name)
+			}
+		}
+	}
+
+	if spec.Root != nil && spec.Root.Readonly && f.Annotations[ReadonlyRootfsAnnotation] == "false" {
+		return errors.New("runtime does not support read-only root filesystems")
+	}
+
+	if f.MountOptions != nil {
+		for _, m := range spec.Mounts {
+			for _, option := range m.Options {
+				if contains(runtimeMountOptions, option) && !contains(f.MountOptions, option) {
+					return fmt.Errorf("runtime does not support mount option '%s' (destination: %s)", option, m.Destination)
+				}
+			}
+		}
+	}
+
+	if spec.Process != nil && spec.Process.Capabilities != nil && f.Linux != nil && f.Linux.Capabilities != nil {
+		caps := spec.Process.Capabilities
+		for _, set := range [][]string{caps.Bounding, caps.Effective, caps.Inheritable, caps.Permitted, caps.Ambient} {
+			for _, c := range set {
+				if !contains(f.Linux.Capabilities, c) {
+					return fmt.Errorf("runtime does not support capability '%s'", c)
+				}
+			}
+		}
+	}
+
+	if spec.Linux == nil || f.Linux == nil {
+		return nil
+	}
+
+	if f.Linux.Namespaces != nil {
+		for _, ns := range spec.Linux.Namespaces {
+			if !contains(f.Linux.Namespaces, string(ns.Type)) {
+				return fmt.Errorf("runtime does not support namespace '%s'", ns.Type)
+			}
+		}
+	}
+
+	if spec.Linux.Seccomp != nil && f.Linux.Seccomp != nil {
+		if err := checkSeccomp(spec.Linux.Seccomp, f.Linux.Seccomp); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SupportsSeccomp returns `false` when an OCI runtime does not support seccomp,
+// and `true` otherwise (including when the features of the runtime are
+// unknown).
+func SupportsSeccomp(f *features.Features) bool {
+	if f == nil || f.Linux == nil || f.Linux.Seccomp == nil || f.Linux.Seccomp.Enabled == nil {
+		return true
+	}
+
+	return *f.Linux.Seccomp.Enabled
+}
+
+func checkSeccomp(config *runtimespec.LinuxSeccomp, f *features.Seccomp) error {
+	if f.Enabled != nil && !*f.Enabled {
+		return fmt.Errorf("runtime does not support seccomp")
+	}
+
+	actions := []runtimespec.LinuxSeccompAction{config.DefaultAction}
+	for _, syscall := range config.Syscalls {
+		actions = append(actions, syscall.Action)
+	}
+
+	if f.Actions != nil {
+		for _, action := range actions {
+			if !contains(f.Actions, string(action)) {
+				return fmt.Errorf("runtime does not support seccomp action '%s'", action)
+			}
+		}
+	}
+
+	if f.Operators != nil {
+		for _, syscall := range config.Syscalls {
+			for _, arg := range syscall.Args {
+				if !contains(f.Operators, string(arg.Op)) {
+					return fmt.Errorf("runtime does not support seccomp operator '%s'", arg.Op)
+				}
+			}
+		}
+	}
+
+	if f.SupportedFlags != nil {
+		for _, flag := range config.Flags {
+			if !contains(f.SupportedFlags, string(flag)) {
+				return fmt.Errorf("runtime does not support seccomp flag '%s'", flag)
+			}
+		}
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,211 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/opencontainers/runtime-spec/specs-go/features"
+)
+
+func TestCheckFeatures(t *testing.T) {
+	disabled := false
+	f := &features.Features{
+		Hooks:        []string{"prestart", "poststop"},
+		MountOptions: []string{"bind", "ro", "nosuid"},
+		Linux: &features.Linux{
+			Namespaces:   []string{"mount", "pid"},
+			Capabilities: []string{"CAP_KILL"},
+			Seccomp: &features.Seccomp{
+				Actions:        []string{"SCMP_ACT_ALLOW", "SCMP_ACT_ERRNO"},
+				Operators:      []string{"SCMP_CMP_EQ"},
+				SupportedFlags: []string{"SECCOMP_FILTER_FLAG_LOG"},
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		spec     runtimespec.Spec
+		f        *features.Features
+		expected string
+	}{
+		{
+			name: "no features",
+			spec: runtimespec.Spec{Hooks: &runtimespec.Hooks{CreateRuntime: []runtimespec.Hook{{Path: "/bin/true"}}}},
+		},
+		{
+			name: "supported",
+			spec: runtimespec.Spec{
+				Hooks:   &runtimespec.Hooks{Prestart: []runtimespec.Hook{{Path: "/bin/true"}}},
+				Mounts:  []runtimespec.Mount{{Destination: "/dev", Options: []string{"nosuid", "mode=755", "newinstance"}}},
+				Process: &runtimespec.Process{Capabilities: &runtimespec.LinuxCapabilities{Bounding: []string{"CAP_KILL"}}},
+				Linux: &runtimespec.Linux{
+					Namespaces: []runtimespec.LinuxNamespace{{Type: "pid"}},
+					Seccomp: &runtimespec.LinuxSeccomp{
+						DefaultAction: runtimespec.ActAllow,
+						Flags:         []runtimespec.LinuxSeccompFlag{"SECCOMP_FILTER_FLAG_LOG"},
+						Syscalls: []runtimespec.LinuxSyscall{
+							{Names: []string{"mount"}, Action: runtimespec.ActErrno, Args: []runtimespec.LinuxSeccompArg{{Op: runtimespec.OpEqualTo}}},
+						},
+					},
+				},
+			},
+			f: f,
+		},
+		{
+			name:     "hook",
+			spec:     runtimespec.Spec{Hooks: &runtimespec.Hooks{CreateRuntime: []runtimespec.Hook{{Path: "/bin/true"}}}},
+			f:        f,
+			expected: "runtime does not support 'createRuntime' hooks",
+		},
+		{
+			name:     "mount option",
+			spec:     runtimespec.Spec{Mounts: []runtimespec.Mount{{Destination: "/data", Options: []string{"rbind", "rro"}}}},
+			f:        f,
+			expected: "runtime does not support mount option 'rbind' (destination: /data)",
+		},
+		{
+			name:     "capability",
+			spec:     runtimespec.Spec{Process: &runtimespec.Process{Capabilities: &runtimespec.LinuxCapabilities{Ambient: []string{"CAP_BPF"}}}},
+			f:        f,
+			expected: "runtime does not support capability 'CAP_BPF'",
+		},
+		{
+			name:     "namespace",
+			spec:     runtimespec.Spec{Linux: &runtimespec.Linux{Namespaces: []runtimespec.LinuxNamespace{{Type: "time"}}}},
+			f:        f,
+			expected: "runtime does not support namespace 'time'",
+		},
+		{
+			name:     "seccomp action",
+			spec:     runtimespec.Spec{Linux: &runtimespec.Linux{Seccomp: &runtimespec.LinuxSeccomp{DefaultAction: runtimespec.ActNotify}}},
+			f:        f,
+			expected: "runtime does not support seccomp action 'SCMP_ACT_NOTIFY'",
+		},
+		{
+			name: "seccomp operator",
+			spec: runtimespec.Spec{Linux: &runtimespec.Linux{Seccomp: &runtimespec.LinuxSeccomp{
+				DefaultAction: runtimespec.ActAllow,
+				Syscalls: []runtimespec.LinuxSyscall{
+					{Names: []string{"mount"}, Action: runtimespec.ActErrno, Args: []runtimespec.LinuxSeccompArg{{Op: runtimespec.OpMaskedEqual}}},
+				},
+			}}},
+			f:        f,
+			expected: "runtime does not support seccomp operator 'SCMP_CMP_MASKED_EQ'",
+		},
+		{
+			name:     "read-only rootfs",
+			spec:     runtimespec.Spec{Root: &runtimespec.Root{Path: "rootfs", Readonly: true}},
+			f:        &features.Features{Annotations: map[string]string{ReadonlyRootfsAnnotation: "false"}},
+			expected: "runtime does not support read-only root filesystems",
+		},
+		{
+			name: "read-only rootfs without annotation",
+			spec: runtimespec.Spec{Root: &runtimespec.Root{Path: "rootfs", Readonly: true}},
+			f:    f,
+		},
+		{
+			name: "seccomp disabled",
+			spec: runtimespec.Spec{Linux: &runtimespec.Linux{Seccomp: &runtimespec.LinuxSeccomp{DefaultAction: runtimespec.ActAllow}}},
+			f: &features.Features{Linux: &features.Linux{
+				Seccomp: &features.Seccomp{Enabled: &disabled},
+			}},
+			expected: "runtime does not support seccomp",
+		},
+	} {
+		err := CheckFeatures(&tc.spec, tc.f)
+		if tc.expected == "" {
+			if err != nil {
+				t.Errorf("%s: expected no error, got: %v", tc.name, err)
+			}
+		} else if err == nil || err.Error() != tc.expected {
+			t.Errorf("%s: expected error: %s, got: %v", tc.name, tc.expected, err)
+		}
+	}
+}
+
+// fakeRuntime creates an executable that prints the given output and exits
+// with the given code when invoked.
+func fakeRuntime(t *testing.T, stdout, stderr string, exitCode int) string {
+	path := filepath.Join(t.TempDir(), "runtime")
+	script := fmt.Sprintf("#!/bin/sh\nprintf '%%s' '%s'\nprintf '%%s' '%s' >&2\nexit %d\n", stdout, stderr, exitCode)
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestQueryFeatures(t *testing.T) {
+	f, err := QueryFeatures(fakeRuntime(t, `{"ociVersionMin":"1.0.0","hooks":["prestart"]}`, "", 0))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if f == nil || f.OCIVersionMin != "1.0.0" || len(f.Hooks) != 1 {
+		t.Errorf("expected features, got: %+v", f)
+	}
+}
+
+func TestQueryFeaturesUnknownCommand(t *testing.T) {
+	for _, stderr := range []string{
+		`Error: unknown command "features" for "microvm"`,
+		"No help topic for 'features'",
+	} {
+		f, err := QueryFeatures(fakeRuntime(t, "", stderr, 1))
+		if err != nil || f != nil {
+			t.Errorf("expected no features and no error, got: %+v, %v", f, err)
+		}
+	}
+}
+
+func TestQueryFeaturesErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{
+			name:     "failure",
+			path:     fakeRuntime(t, "", "oops", 2),
+			expected: "failed to query runtime features: exit status 2 (stderr: oops)",
+		},
+		{
+			name:     "invalid output",
+			path:     fakeRuntime(t, "not json", "", 0),
+			expected: "failed to parse runtime features",
+		},
+		{
+			name:     "not found",
+			path:     filepath.Join(t.TempDir(), "does-not-exist"),
+			expected: "failed to query runtime features",
+		},
+	} {
+		_, err := QueryFeatures(tc.path)
+		if err == nil || !strings.HasPrefix(err.Error(), tc.expected) {
+			t.Errorf("%s: expected error: %s, got: %v", tc.name, tc.expected, err)
+		}
+	}
+}
+
+func TestSupportsSeccomp(t *testing.T) {
+	enabled, disabled := true, false
+
+	for _, tc := range []struct {
+		f        *features.Features
+		expected bool
+	}{
+		{f: nil, expected: true},
+		{f: &features.Features{}, expected: true},
+		{f: &features.Features{Linux: &features.Linux{Seccomp: &features.Seccomp{Enabled: &enabled}}}, expected: true},
+		{f: &features.Features{Linux: &features.Linux{Seccomp: &features.Seccomp{Enabled: &disabled}}}, expected: false},
+	} {
+		if supported := SupportsSeccomp(tc.f); supported != tc.expected {
+			t.Errorf("%+v: expected %t, got: %t", tc.f, tc.expected, supported)
+		}
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strings"
 
 	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/willdurand/containers/internal/user"
@@ -23,13 +22,25 @@ func LoadSpec(bundleDir string) (runtimespec.Spec, error) {
 		return spec, fmt.Errorf("failed to parse config.json: %w", err)
 	}
 
-	if !strings.HasPrefix(spec.Version, "1.0") {
+	if !supportedVersion(spec.Version) {
 		return spec, fmt.Errorf("unsupported runtime configuration version '%s'", spec.Version)
 	}
 
 	return spec, nil
 }
 
+// supportedVersion returns `true` when a runtime configuration version is
+// compatible with the runtime-spec version used by this project, i.e. when it
+// has the same major version and a minor version that is not greater.
+func supportedVersion(version string) bool {
+	var major, minor int
+	if _, err := fmt.Sscanf(version, "%d.%d", &major, &minor); err != nil {
+		return false
+	}
+
+	return major == runtimespec.VersionMajor && minor <= runtimespec.VersionMinor
+}
+
 func BaseSpec(rootfs string, rootless bool) (*runtimespec.Spec, error) {
 	mounts := []runtimespec.Mount{
 		{
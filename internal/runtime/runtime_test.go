@@ -0,0 +1,26 @@
+package runtime
+
+import "testing"
+
+func TestSupportedVersion(t *testing.T) {
+	for _, tc := range []struct {
+		version  string
+		expected bool
+	}{
+		{"1.0.0", true},
+		{"1.0.2-dev", true},
+		{"1.1.0", true},
+		{"1.2.0", true},
+		{"1.2.1", true},
+		{"1.3.0", false},
+		{"1.9.0", false},
+		{"2.0.0", false},
+		{"0.5.0", false},
+		{"", false},
+		{"latest", false},
+	} {
+		if got := supportedVersion(tc.version); got != tc.expected {
+			t.Errorf("%s: expected: %t, got: %t", tc.version, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package yacr
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"golang.org/x/sys/unix"
+)
+
+// cloneBinary copies the current executable into a sealed (read-only) memfd
+// and returns it. Processes created in the namespaces of a container must be
+// executed from this copy rather than from the yacr binary on the host because
+// a malicious container could otherwise overwrite the host binary through
+// `/proc/<pid>/exe` (CVE-2019-5736).
+func cloneBinary() (*os.File, error) {
+	exe, err := os.Open("/proc/self/exe")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open executable: %w", err)
+	}
+	defer exe.Close()
+
+	fd, err := unix.MemfdCreate("yacr", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create memfd: %w", err)
+	}
+	memfd := os.NewFile(uintptr(fd), "yacr")
+
+	if _, err := io.Copy(memfd, exe); err != nil {
+		memfd.Close()
+		return nil, fmt.Errorf("failed to copy executable: %w", err)
+	}
+
+	seals := unix.F_SEAL_SEAL | unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE
+	if _, err := unix.FcntlInt(memfd.Fd(), unix.F_ADD_SEALS, seals); err != nil {
+		memfd.Close()
+		return nil, fmt.Errorf("failed to seal memfd: %w", err)
+	}
+
+	return memfd, nil
+}
+
+// useBinary configures a command to execute a binary returned by
+// `cloneBinary()`. The binary is passed to the process as its last extra file
+// so that the file descriptor used by execve(2) is known (the other file
+// descriptors might be moved around before that). This function must be
+// called once the other extra files have been set.
+func useBinary(cmd *exec.Cmd, binary *os.File) {
+	cmd.ExtraFiles = append(cmd.ExtraFiles, binary)
+	cmd.Path = fmt.Sprintf("/proc/self/fd/%d", 2+len(cmd.ExtraFiles))
+}
@@ -0,0 +1,29 @@
+package yacr
+
+import (
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestCloneBinary(t *testing.T) {
+	binary, err := cloneBinary()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer binary.Close()
+
+	seals, err := unix.FcntlInt(binary.Fd(), unix.F_GET_SEALS, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	expected := unix.F_SEAL_SEAL | unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE
+	if seals != expected {
+		t.Errorf("expected seals: %#x, got: %#x", expected, seals)
+	}
+
+	if _, err := binary.WriteAt([]byte("oops"), 0); err == nil {
+		t.Error("expected an error when writing to the binary")
+	}
+}
@@ -0,0 +1,200 @@
+package yacr
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+// capabilityNames maps the capability names used in a runtime configuration
+// to their values.
+var capabilityNames = map[string]int{
+	"CAP_CHOWN":              unix.CAP_CHOWN,
+	"CAP_DAC_OVERRIDE":       unix.CAP_DAC_OVERRIDE,
+	"CAP_DAC_READ_SEARCH":    unix.CAP_DAC_READ_SEARCH,
+	"CAP_FOWNER":             unix.CAP_FOWNER,
+	"CAP_FSETID":             unix.CAP_FSETID,
+	"CAP_KILL":               unix.CAP_KILL,
+	"CAP_SETGID":             unix.CAP_SETGID,
+	"CAP_SETUID":             unix.CAP_SETUID,
+	"CAP_SETPCAP":            unix.CAP_SETPCAP,
+	"CAP_LINUX_IMMUTABLE":    unix.CAP_LINUX_IMMUTABLE,
+	"CAP_NET_BIND_SERVICE":   unix.CAP_NET_BIND_SERVICE,
+	"CAP_NET_BROADCAST":      unix.CAP_NET_BROADCAST,
+	"CAP_NET_ADMIN":          unix.CAP_NET_ADMIN,
+	"CAP_NET_RAW":            unix.CAP_NET_RAW,
+	"CAP_IPC_LOCK":           unix.CAP_IPC_LOCK,
+	"CAP_IPC_OWNER":          unix.CAP_IPC_OWNER,
+	"CAP_SYS_MODULE":         unix.CAP_SYS_MODULE,
+	"CAP_SYS_RAWIO":          unix.CAP_SYS_RAWIO,
+	"CAP_SYS_CHROOT":         unix.CAP_SYS_CHROOT,
+	"CAP_SYS_PTRACE":         unix.CAP_SYS_PTRACE,
+	"CAP_SYS_PACCT":          unix.CAP_SYS_PACCT,
+	"CAP_SYS_ADMIN":          unix.CAP_SYS_ADMIN,
+	"CAP_SYS_BOOT":           unix.CAP_SYS_BOOT,
+	"CAP_SYS_NICE":           unix.CAP_SYS_NICE,
+	"CAP_SYS_RESOURCE":       unix.CAP_SYS_RESOURCE,
+	"CAP_SYS_TIME":           unix.CAP_SYS_TIME,
+	"CAP_SYS_TTY_CONFIG":     unix.CAP_SYS_TTY_CONFIG,
+	"CAP_MKNOD":              unix.CAP_MKNOD,
+	"CAP_LEASE":              unix.CAP_LEASE,
+	"CAP_AUDIT_WRITE":        unix.CAP_AUDIT_WRITE,
+	"CAP_AUDIT_CONTROL":      unix.CAP_AUDIT_CONTROL,
+	"CAP_SETFCAP":            unix.CAP_SETFCAP,
+	"CAP_MAC_OVERRIDE":       unix.CAP_MAC_OVERRIDE,
+	"CAP_MAC_ADMIN":          unix.CAP_MAC_ADMIN,
+	"CAP_SYSLOG":             unix.CAP_SYSLOG,
+	"CAP_WAKE_ALARM":         unix.CAP_WAKE_ALARM,
+	"CAP_BLOCK_SUSPEND":      unix.CAP_BLOCK_SUSPEND,
+	"CAP_AUDIT_READ":         unix.CAP_AUDIT_READ,
+	"CAP_PERFMON":            unix.CAP_PERFMON,
+	"CAP_BPF":                unix.CAP_BPF,
+	"CAP_CHECKPOINT_RESTORE": unix.CAP_CHECKPOINT_RESTORE,
+}
+
+// capabilities represents the different capability sets of a process.
+//
+// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/config.md#linux-process
+type capabilities struct {
+	bounding    []int
+	effective   []int
+	inheritable []int
+	permitted   []int
+	ambient     []int
+}
+
+// newCapabilities parses the capability sets of a runtime configuration. It
+// returns an error when a capability is unknown.
+func newCapabilities(caps *runtimespec.LinuxCapabilities) (*capabilities, error) {
+	if caps == nil {
+		return nil, nil
+	}
+
+	c := new(capabilities)
+	for _, set := range []struct {
+		names []string
+		dest  *[]int
+	}{
+		{caps.Bounding, &c.bounding},
+		{caps.Effective, &c.effective},
+		{caps.Inheritable, &c.inheritable},
+		{caps.Permitted, &c.permitted},
+		{caps.Ambient, &c.ambient},
+	} {
+		for _, name := range set.names {
+			value, ok := capabilityNames[strings.ToUpper(name)]
+			if !ok {
+				return nil, fmt.Errorf("unknown capability '%s'", name)
+			}
+			*set.dest = append(*set.dest, value)
+		}
+	}
+
+	return c, nil
+}
+
+// applyBoundingSet drops all the capabilities that are not in the bounding
+// set. This requires `CAP_SETPCAP` so it must be called before the other
+// capability sets are applied.
+func (c *capabilities) applyBoundingSet() error {
+	if c == nil {
+		return nil
+	}
+
+	for value := 0; value <= lastCapability(); value++ {
+		if containsCapability(c.bounding, value) {
+			continue
+		}
+
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(value), 0, 0, 0); err != nil {
+			// EINVAL means that the capability is not supported by the kernel.
+			if errors.Is(err, unix.EINVAL) {
+				continue
+			}
+			return fmt.Errorf("failed to drop %s from bounding set: %w", capabilityName(value), err)
+		}
+	}
+
+	return nil
+}
+
+// apply sets the effective, permitted, inheritable and ambient capability
+// sets of the current thread.
+func (c *capabilities) apply() error {
+	if c == nil {
+		return nil
+	}
+
+	// We need two `CapUserData` structs because of the 64-bit capabilities.
+	// See: https://man7.org/linux/man-pages/man2/capset.2.html
+	var data [2]unix.CapUserData
+	for _, value := range c.effective {
+		data[value/32].Effective |= 1 << uint(value%32)
+	}
+	for _, value := range c.permitted {
+		data[value/32].Permitted |= 1 << uint(value%32)
+	}
+	for _, value := range c.inheritable {
+		data[value/32].Inheritable |= 1 << uint(value%32)
+	}
+
+	header := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	if err := unix.Capset(&header, &data[0]); err != nil {
+		return fmt.Errorf("failed to set capabilities: %w", err)
+	}
+
+	// The ambient set is cleared and then raised capability by capability. A
+	// capability must be both permitted and inheritable to be raised.
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to clear ambient capabilities: %w", err)
+	}
+	for _, value := range c.ambient {
+		if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_RAISE, uintptr(value), 0, 0); err != nil {
+			return fmt.Errorf("failed to raise ambient capability %s: %w", capabilityName(value), err)
+		}
+	}
+
+	return nil
+}
+
+// lastCapability returns the highest capability supported by the running
+// kernel.
+func lastCapability() int {
+	data, err := os.ReadFile("/proc/sys/kernel/cap_last_cap")
+	if err != nil {
+		return unix.CAP_LAST_CAP
+	}
+
+	value, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return unix.CAP_LAST_CAP
+	}
+
+	return value
+}
+
+// capabilityName returns the name of a capability given its value.
+func capabilityName(value int) string {
+	for name, v := range capabilityNames {
+		if v == value {
+			return name
+		}
+	}
+
+	return strconv.Itoa(value)
+}
+
+func containsCapability(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,50 @@
+package yacr
+
+import (
+	"testing"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+func TestNewCapabilities(t *testing.T) {
+	caps, err := newCapabilities(&runtimespec.LinuxCapabilities{
+		Bounding:  []string{"CAP_KILL", "CAP_NET_BIND_SERVICE"},
+		Effective: []string{"cap_kill"},
+	})
+	if err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+
+	if len(caps.bounding) != 2 || caps.bounding[1] != unix.CAP_NET_BIND_SERVICE {
+		t.Errorf("unexpected bounding set: %v", caps.bounding)
+	}
+
+	if len(caps.effective) != 1 || caps.effective[0] != unix.CAP_KILL {
+		t.Errorf("unexpected effective set: %v", caps.effective)
+	}
+
+	if len(caps.ambient) != 0 {
+		t.Errorf("expected empty ambient set, got: %v", caps.ambient)
+	}
+}
+
+func TestNewCapabilitiesNil(t *testing.T) {
+	caps, err := newCapabilities(nil)
+	if err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+
+	if caps != nil {
+		t.Errorf("expected no capabilities, got: %v", caps)
+	}
+}
+
+func TestNewCapabilitiesUnknown(t *testing.T) {
+	_, err := newCapabilities(&runtimespec.LinuxCapabilities{
+		Permitted: []string{"CAP_KILL", "CAP_UNKNOWN"},
+	})
+	if err == nil || err.Error() != "unknown capability 'CAP_UNKNOWN'" {
+		t.Errorf("expected unknown capability error, got: %v", err)
+	}
+}
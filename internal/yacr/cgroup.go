@@ -0,0 +1,73 @@
+package yacr
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"github.com/willdurand/containers/internal/yacr/cgroups"
+	"github.com/willdurand/containers/internal/yacr/container"
+)
+
+// setupCgroup creates the cgroup of a container, applies the resource limits
+// and moves the container process into it. In rootless mode, yacr is usually
+// not allowed to create cgroups, which is fine as long as there is no limit to
+// enforce.
+func setupCgroup(container *container.YacrContainer, pid int) error {
+	resources := container.Spec.Linux.Resources
+	rootless := isRootless(container.Spec)
+
+	cgroup, err := container.Cgroup()
+	if err != nil {
+		return err
+	}
+
+	created, err := cgroup.Create()
+	if created {
+		err = container.SaveCgroupCreated()
+	}
+	if err == nil {
+		err = cgroup.Apply(resources)
+	}
+	// Attaching an eBPF program to a cgroup requires privileges that yacr does
+	// not have in rootless mode.
+	if err == nil {
+		if !rootless {
+			err = cgroup.ApplyDevices(deviceCgroupRules(container.Spec))
+		} else if resources != nil && len(resources.Devices) > 0 {
+			logrus.WithFields(logrus.Fields{
+				"id":      container.ID(),
+				"devices": resources.Devices,
+			}).Warn("device cgroup rules cannot be enforced in rootless mode")
+		}
+	}
+	if err == nil {
+		err = cgroup.AddProcess(pid)
+	}
+
+	if err != nil {
+		if cgroups.HasLimits(resources) || !(rootless || errors.Is(err, cgroups.ErrNotUnified)) {
+			return err
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"id":    container.ID(),
+			"error": err,
+		}).Warn("skipping cgroup setup")
+
+		if created {
+			if err := cgroup.Destroy(); err != nil {
+				logrus.WithError(err).Debug("failed to destroy cgroup")
+			}
+		}
+
+		return nil
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":     container.ID(),
+		"cgroup": cgroup.Path,
+		"pid":    pid,
+	}).Debug("container process added to cgroup")
+
+	return nil
+}
@@ -0,0 +1,237 @@
+// Package cgroups implements the (minimal) cgroup v2 support needed by yacr to
+// enforce the resource limits of a container.
+package cgroups
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// Root is the mount point of the unified cgroup hierarchy.
+	Root string = "/sys/fs/cgroup"
+	// defaultParent is the parent cgroup of the containers that do not specify
+	// a `cgroupsPath` in their configuration.
+	defaultParent string = "yacr"
+)
+
+var (
+	ErrNotUnified = errors.New("cgroup v2 (unified hierarchy) is not available")
+
+	// controllers is the list of controllers that yacr enables for the
+	// containers' cgroups.
+	controllers = []string{"cpu", "cpuset", "hugetlb", "io", "memory", "pids", "rdma"}
+)
+
+// Cgroup represents the cgroup of a container.
+type Cgroup struct {
+	Path string
+}
+
+// New returns the cgroup of a container given the `cgroupsPath` value of its
+// runtime configuration. When this value is empty, a default path derived from
+// the container ID is used.
+//
+// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/config-linux.md#cgroups-path
+func New(cgroupsPath, containerId string) (*Cgroup, error) {
+	if strings.Contains(cgroupsPath, ":") {
+		return nil, fmt.Errorf("systemd cgroups path '%s' is not supported", cgroupsPath)
+	}
+
+	if cgroupsPath == "" {
+		cgroupsPath = filepath.Join(defaultParent, containerId)
+	}
+
+	// Absolute and relative paths are both relative to the cgroup root, and
+	// `filepath.Join()` cleans the path so that it cannot escape the root.
+	path := filepath.Join(Root, filepath.Join("/", cgroupsPath))
+	if path == Root {
+		return nil, fmt.Errorf("invalid cgroups path '%s'", cgroupsPath)
+	}
+
+	return &Cgroup{Path: path}, nil
+}
+
+// IsUnified returns `true` when the cgroup v2 hierarchy is mounted on `Root`,
+// and `false` otherwise.
+func IsUnified() bool {
+	var st unix.Statfs_t
+	if err := unix.Statfs(Root, &st); err != nil {
+		return false
+	}
+
+	return st.Type == unix.CGROUP2_SUPER_MAGIC
+}
+
+// Create creates the cgroup directory (and its parents when needed) and
+// enables the controllers used by yacr along the way. It returns `true` when
+// the cgroup directory has been created, and `false` when it already existed.
+func (c *Cgroup) Create() (bool, error) {
+	if !IsUnified() {
+		return false, ErrNotUnified
+	}
+
+	rel, err := filepath.Rel(Root, c.Path)
+	if err != nil {
+		return false, err
+	}
+
+	created := false
+	current := Root
+	for _, elem := range strings.Split(rel, string(filepath.Separator)) {
+		enableControllers(current)
+
+		current = filepath.Join(current, elem)
+		err := os.Mkdir(current, 0o755)
+		if err != nil && !errors.Is(err, fs.ErrExist) {
+			return false, fmt.Errorf("failed to create cgroup '%s': %w", current, err)
+		}
+		created = err == nil
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"path":    c.Path,
+		"created": created,
+	}).Debug("cgroup created")
+
+	return created, nil
+}
+
+// AddProcess moves a process into the cgroup.
+func (c *Cgroup) AddProcess(pid int) error {
+	if err := c.write("cgroup.procs", strconv.Itoa(pid)); err != nil {
+		return fmt.Errorf("failed to add process %d to cgroup: %w", pid, err)
+	}
+
+	return nil
+}
+
+// Processes returns the PIDs of the processes in the cgroup.
+func (c *Cgroup) Processes() ([]int, error) {
+	data, err := os.ReadFile(filepath.Join(c.Path, "cgroup.procs"))
+	if err != nil {
+		return nil, err
+	}
+
+	var pids []int
+	for _, line := range strings.Fields(string(data)) {
+		pid, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse cgroup.procs: %w", err)
+		}
+		pids = append(pids, pid)
+	}
+
+	return pids, nil
+}
+
+// Exists returns `true` when the cgroup directory exists, and `false`
+// otherwise.
+func (c *Cgroup) Exists() bool {
+	_, err := os.Stat(c.Path)
+	return err == nil
+}
+
+// Destroy kills the processes that are still in the cgroup (if any) and
+// removes the cgroup directory. It does not return an error when the cgroup
+// does not exist. It should only be called for a cgroup returned as created by
+// `Create()`.
+func (c *Cgroup) Destroy() error {
+	if !c.Exists() {
+		return nil
+	}
+
+	// `cgroup.kill` is only available since Linux 5.14.
+	if err := c.write("cgroup.kill", "1"); err != nil {
+		logrus.WithError(err).Debug("failed to write to cgroup.kill")
+	}
+
+	var err error
+	// Removing a cgroup fails with EBUSY as long as the killed processes have
+	// not been reaped, so we retry a few times.
+	for i := 0; i < 20; i++ {
+		err = os.Remove(c.Path)
+		if err == nil || errors.Is(err, fs.ErrNotExist) {
+			logrus.WithField("path", c.Path).Debug("cgroup destroyed")
+			return nil
+		}
+		if !errors.Is(err, unix.EBUSY) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return fmt.Errorf("failed to remove cgroup '%s': %w", c.Path, err)
+}
+
+// write writes a value to one of the cgroup interface files. Files cannot be
+// created in a cgroup directory so we never pass `O_CREATE`.
+func (c *Cgroup) write(name, value string) error {
+	return writeFile(filepath.Join(c.Path, name), value)
+}
+
+func writeFile(path, value string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(value); err != nil {
+		return fmt.Errorf("failed to write '%s' to %s: %w", value, path, err)
+	}
+
+	return nil
+}
+
+// enableControllers enables the controllers used by yacr in the
+// `cgroup.subtree_control` file of the given cgroup. This is a best effort
+// operation because a controller might not be available or the cgroup might
+// already have processes in it (no internal process constraint). Missing
+// controllers will be reported when the limits are applied.
+func enableControllers(dir string) {
+	data, err := os.ReadFile(filepath.Join(dir, "cgroup.controllers"))
+	if err != nil {
+		logrus.WithError(err).WithField("cgroup", dir).Debug("failed to read cgroup.controllers")
+		return
+	}
+
+	available := strings.Fields(string(data))
+	for _, controller := range controllers {
+		if !contains(available, controller) {
+			continue
+		}
+
+		if err := writeFile(filepath.Join(dir, "cgroup.subtree_control"), "+"+controller); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"cgroup":     dir,
+				"controller": controller,
+				"error":      err,
+			}).Debug("failed to enable controller")
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Controllers returns the list of controllers used by yacr.
+func Controllers() []string {
+	return append([]string(nil), controllers...)
+}
@@ -0,0 +1,233 @@
+package cgroups
+
+import (
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+// bpfInsn represents an eBPF instruction (`struct bpf_insn`). The destination
+// register is stored in the lower 4 bits of `regs` and the source register in
+// the upper 4 bits.
+type bpfInsn struct {
+	code uint8
+	regs uint8
+	off  int16
+	imm  int32
+}
+
+// bpfProgLoadAttr is the part of `union bpf_attr` used by `BPF_PROG_LOAD`.
+type bpfProgLoadAttr struct {
+	progType    uint32
+	insnCnt     uint32
+	insns       uint64
+	license     uint64
+	logLevel    uint32
+	logSize     uint32
+	logBuf      uint64
+	kernVersion uint32
+	progFlags   uint32
+	progName    [16]byte
+}
+
+// bpfProgAttachAttr is the part of `union bpf_attr` used by `BPF_PROG_ATTACH`.
+type bpfProgAttachAttr struct {
+	targetFd    uint32
+	attachBpfFd uint32
+	attachType  uint32
+	attachFlags uint32
+}
+
+// Registers used by the device filter. The context (`struct
+// bpf_cgroup_dev_ctx`) is passed in R1 and the return value is R0.
+const (
+	r0 uint8 = iota
+	r1
+	r2
+	r3
+	r4
+	r5
+)
+
+// ApplyDevices enforces the device cgroup rules of a container. With cgroup
+// v2, there is no interface file for that and an eBPF program has to be
+// attached to the cgroup instead.
+//
+// See: https://docs.kernel.org/admin-guide/cgroup-v2.html#device-controller
+func (c *Cgroup) ApplyDevices(rules []runtimespec.LinuxDeviceCgroup) error {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	insns, err := compileDeviceFilter(rules)
+	if err != nil {
+		return err
+	}
+
+	license := []byte("Apache\x00")
+	loadAttr := bpfProgLoadAttr{
+		progType: unix.BPF_PROG_TYPE_CGROUP_DEVICE,
+		insnCnt:  uint32(len(insns)),
+		insns:    uint64(uintptr(unsafe.Pointer(&insns[0]))),
+		license:  uint64(uintptr(unsafe.Pointer(&license[0]))),
+	}
+	progFd, _, errno := unix.Syscall(unix.SYS_BPF, unix.BPF_PROG_LOAD, uintptr(unsafe.Pointer(&loadAttr)), unsafe.Sizeof(loadAttr))
+	// The instructions and the license are only referenced by (integer) fields
+	// of the attributes so they must be kept alive until the syscall returns.
+	runtime.KeepAlive(insns)
+	runtime.KeepAlive(license)
+	if errno != 0 {
+		return fmt.Errorf("failed to load device filter: %w", errno)
+	}
+	// The program stays alive as long as it is attached to the cgroup.
+	defer unix.Close(int(progFd))
+
+	dirFd, err := unix.Open(c.Path, unix.O_DIRECTORY|unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open cgroup: %w", err)
+	}
+	defer unix.Close(dirFd)
+
+	// Without `BPF_F_ALLOW_MULTI`, attaching a new program replaces the
+	// existing one, if any.
+	attachAttr := bpfProgAttachAttr{
+		targetFd:    uint32(dirFd),
+		attachBpfFd: uint32(progFd),
+		attachType:  unix.BPF_CGROUP_DEVICE,
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_BPF, unix.BPF_PROG_ATTACH, uintptr(unsafe.Pointer(&attachAttr)), unsafe.Sizeof(attachAttr)); errno != 0 {
+		return fmt.Errorf("failed to attach device filter: %w", errno)
+	}
+
+	return nil
+}
+
+// compileDeviceFilter converts device cgroup rules into an eBPF program that
+// returns 1 when an access is allowed and 0 otherwise.
+//
+// Like with cgroup v1, the last rule matching a device wins and the accesses
+// that are not matched by any rule are allowed.
+func compileDeviceFilter(rules []runtimespec.LinuxDeviceCgroup) ([]bpfInsn, error) {
+	insns := []bpfInsn{
+		// R2 = type
+		ldxMemW(r2, r1, 0),
+		aluImm(unix.BPF_AND, r2, 0xffff),
+		// R3 = access
+		ldxMemW(r3, r1, 0),
+		aluImm(unix.BPF_RSH, r3, 16),
+		// R4 = major
+		ldxMemW(r4, r1, 4),
+		// R5 = minor
+		ldxMemW(r5, r1, 8),
+	}
+
+	for i := len(rules) - 1; i >= 0; i-- {
+		block, err := compileDeviceRule(rules[i])
+		if err != nil {
+			return nil, err
+		}
+		insns = append(insns, block...)
+
+		// A rule without conditions matches all devices, which makes the rules
+		// before it unreachable. The verifier rejects unreachable instructions.
+		if len(block) == 2 {
+			return insns, nil
+		}
+	}
+
+	return append(insns, movImm(r0, 1), exit()), nil
+}
+
+// compileDeviceRule converts a device cgroup rule into a block of
+// instructions. The conditions jump to the end of the block (i.e. the next
+// rule) when the device does not match.
+func compileDeviceRule(rule runtimespec.LinuxDeviceCgroup) ([]bpfInsn, error) {
+	var block []bpfInsn
+
+	switch rule.Type {
+	case "", "a":
+	case "b":
+		block = append(block, jneImm(r2, unix.BPF_DEVCG_DEV_BLOCK))
+	case "c":
+		block = append(block, jneImm(r2, unix.BPF_DEVCG_DEV_CHAR))
+	default:
+		return nil, fmt.Errorf("invalid device type '%s'", rule.Type)
+	}
+
+	access := 0
+	for _, a := range rule.Access {
+		switch a {
+		case 'm':
+			access |= unix.BPF_DEVCG_ACC_MKNOD
+		case 'r':
+			access |= unix.BPF_DEVCG_ACC_READ
+		case 'w':
+			access |= unix.BPF_DEVCG_ACC_WRITE
+		default:
+			return nil, fmt.Errorf("invalid device access '%s'", rule.Access)
+		}
+	}
+
+	// An empty access is the same as "rwm". Otherwise, the requested access
+	// must be a subset of the access of the rule.
+	if access != 0 && access != unix.BPF_DEVCG_ACC_MKNOD|unix.BPF_DEVCG_ACC_READ|unix.BPF_DEVCG_ACC_WRITE {
+		block = append(block,
+			movReg(r1, r3),
+			aluImm(unix.BPF_AND, r1, int32(access)),
+			jneReg(r1, r3),
+		)
+	}
+
+	if rule.Major != nil && *rule.Major >= 0 {
+		block = append(block, jneImm(r4, int32(*rule.Major)))
+	}
+	if rule.Minor != nil && *rule.Minor >= 0 {
+		block = append(block, jneImm(r5, int32(*rule.Minor)))
+	}
+
+	allow := int32(0)
+	if rule.Allow {
+		allow = 1
+	}
+	block = append(block, movImm(r0, allow), exit())
+
+	// Jump to the end of the block on mismatch.
+	for i := range block {
+		if block[i].code&0x07 == unix.BPF_JMP && block[i].code != unix.BPF_JMP|unix.BPF_EXIT {
+			block[i].off = int16(len(block) - i - 1)
+		}
+	}
+
+	return block, nil
+}
+
+func ldxMemW(dst, src uint8, off int16) bpfInsn {
+	return bpfInsn{code: unix.BPF_LDX | unix.BPF_MEM | unix.BPF_W, regs: src<<4 | dst, off: off}
+}
+
+func aluImm(op uint8, dst uint8, imm int32) bpfInsn {
+	return bpfInsn{code: unix.BPF_ALU | op | unix.BPF_K, regs: dst, imm: imm}
+}
+
+func movImm(dst uint8, imm int32) bpfInsn {
+	return bpfInsn{code: unix.BPF_ALU64 | unix.BPF_MOV | unix.BPF_K, regs: dst, imm: imm}
+}
+
+func movReg(dst, src uint8) bpfInsn {
+	return bpfInsn{code: unix.BPF_ALU64 | unix.BPF_MOV | unix.BPF_X, regs: src<<4 | dst}
+}
+
+func jneImm(dst uint8, imm int32) bpfInsn {
+	return bpfInsn{code: unix.BPF_JMP | unix.BPF_JNE | unix.BPF_K, regs: dst, imm: imm}
+}
+
+func jneReg(dst, src uint8) bpfInsn {
+	return bpfInsn{code: unix.BPF_JMP | unix.BPF_JNE | unix.BPF_X, regs: src<<4 | dst}
+}
+
+func exit() bpfInsn {
+	return bpfInsn{code: unix.BPF_JMP | unix.BPF_EXIT}
+}
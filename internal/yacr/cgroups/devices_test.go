@@ -0,0 +1,127 @@
+package cgroups
+
+import (
+	"testing"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+// runDeviceFilter interprets the subset of eBPF used by the device filter.
+func runDeviceFilter(t *testing.T, insns []bpfInsn, devType, access, major, minor uint32) uint64 {
+	ctx := [3]uint32{access<<16 | devType, major, minor}
+	var regs [11]uint64
+
+	for pc := 0; pc < len(insns); pc++ {
+		insn := insns[pc]
+		dst, src := insn.regs&0x0f, insn.regs>>4
+
+		switch insn.code {
+		case unix.BPF_LDX | unix.BPF_MEM | unix.BPF_W:
+			regs[dst] = uint64(ctx[insn.off/4])
+		case unix.BPF_ALU | unix.BPF_AND | unix.BPF_K:
+			regs[dst] = uint64(uint32(regs[dst]) & uint32(insn.imm))
+		case unix.BPF_ALU | unix.BPF_RSH | unix.BPF_K:
+			regs[dst] = uint64(uint32(regs[dst]) >> insn.imm)
+		case unix.BPF_ALU64 | unix.BPF_MOV | unix.BPF_K:
+			regs[dst] = uint64(int64(insn.imm))
+		case unix.BPF_ALU64 | unix.BPF_MOV | unix.BPF_X:
+			regs[dst] = regs[src]
+		case unix.BPF_JMP | unix.BPF_JNE | unix.BPF_K:
+			if regs[dst] != uint64(int64(insn.imm)) {
+				pc += int(insn.off)
+			}
+		case unix.BPF_JMP | unix.BPF_JNE | unix.BPF_X:
+			if regs[dst] != regs[src] {
+				pc += int(insn.off)
+			}
+		case unix.BPF_JMP | unix.BPF_EXIT:
+			return regs[0]
+		default:
+			t.Fatalf("unexpected instruction: %+v", insn)
+		}
+	}
+
+	t.Fatal("program did not exit")
+	return 0
+}
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+func TestCompileDeviceFilter(t *testing.T) {
+	insns, err := compileDeviceFilter([]runtimespec.LinuxDeviceCgroup{
+		{Allow: false, Access: "rwm"},
+		{Allow: true, Type: "c", Major: int64Ptr(1), Minor: int64Ptr(3), Access: "rwm"},
+		{Allow: true, Type: "c", Major: int64Ptr(136), Access: "rw"},
+		{Allow: false, Type: "c", Major: int64Ptr(136), Minor: int64Ptr(0)},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	for _, tc := range []struct {
+		devType, access, major, minor uint32
+		expected                      uint64
+	}{
+		// /dev/null
+		{unix.BPF_DEVCG_DEV_CHAR, unix.BPF_DEVCG_ACC_READ, 1, 3, 1},
+		{unix.BPF_DEVCG_DEV_CHAR, unix.BPF_DEVCG_ACC_MKNOD, 1, 3, 1},
+		{unix.BPF_DEVCG_DEV_BLOCK, unix.BPF_DEVCG_ACC_READ, 1, 3, 0},
+		// /dev/zero
+		{unix.BPF_DEVCG_DEV_CHAR, unix.BPF_DEVCG_ACC_READ, 1, 5, 0},
+		// /dev/pts/*
+		{unix.BPF_DEVCG_DEV_CHAR, unix.BPF_DEVCG_ACC_READ | unix.BPF_DEVCG_ACC_WRITE, 136, 2, 1},
+		{unix.BPF_DEVCG_DEV_CHAR, unix.BPF_DEVCG_ACC_MKNOD, 136, 2, 0},
+		{unix.BPF_DEVCG_DEV_CHAR, unix.BPF_DEVCG_ACC_READ, 136, 0, 0},
+	} {
+		if result := runDeviceFilter(t, insns, tc.devType, tc.access, tc.major, tc.minor); result != tc.expected {
+			t.Errorf("%+v: expected %d, got: %d", tc, tc.expected, result)
+		}
+	}
+}
+
+func TestCompileDeviceFilterDefaultAllow(t *testing.T) {
+	insns, err := compileDeviceFilter([]runtimespec.LinuxDeviceCgroup{
+		{Allow: false, Type: "b", Access: "w"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if result := runDeviceFilter(t, insns, unix.BPF_DEVCG_DEV_CHAR, unix.BPF_DEVCG_ACC_WRITE, 1, 3); result != 1 {
+		t.Errorf("expected 1, got: %d", result)
+	}
+	if result := runDeviceFilter(t, insns, unix.BPF_DEVCG_DEV_BLOCK, unix.BPF_DEVCG_ACC_WRITE, 8, 0); result != 0 {
+		t.Errorf("expected 0, got: %d", result)
+	}
+}
+
+func TestCompileDeviceFilterShadowedRules(t *testing.T) {
+	insns, err := compileDeviceFilter([]runtimespec.LinuxDeviceCgroup{
+		{Allow: true, Type: "c", Major: int64Ptr(1), Minor: int64Ptr(3)},
+		{Allow: false},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if result := runDeviceFilter(t, insns, unix.BPF_DEVCG_DEV_CHAR, unix.BPF_DEVCG_ACC_READ, 1, 3); result != 0 {
+		t.Errorf("expected 0, got: %d", result)
+	}
+	if last := insns[len(insns)-2]; last.imm != 0 {
+		t.Errorf("expected the program to end with the last rule, got: %+v", last)
+	}
+}
+
+func TestCompileDeviceFilterInvalid(t *testing.T) {
+	for _, rule := range []runtimespec.LinuxDeviceCgroup{
+		{Type: "x"},
+		{Access: "rwx"},
+	} {
+		if _, err := compileDeviceFilter([]runtimespec.LinuxDeviceCgroup{rule}); err == nil {
+			t.Errorf("expected an error for: %+v", rule)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package cgroups
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// EventOOM is sent by Watch when the OOM killer has killed a process of the
+// cgroup.
+const EventOOM = "oom"
+
+// Watch watches the interface files of the cgroup and sends an EventOOM when
+// the OOM killer has been invoked. The returned channel is closed when the
+// cgroup does not have any process left, when it is removed or when `done` is
+// closed.
+func (c *Cgroup) Watch(done <-chan struct{}) (<-chan string, error) {
+	if !IsUnified() {
+		return nil, ErrNotUnified
+	}
+
+	// A non-blocking descriptor is required so that reads can be interrupted
+	// by closing the file below.
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify_init1: %w", err)
+	}
+	file := os.NewFile(uintptr(fd), "inotify")
+
+	for _, name := range []string{"cgroup.events", "memory.events"} {
+		_, err := unix.InotifyAddWatch(fd, filepath.Join(c.Path, name), unix.IN_MODIFY)
+		// `memory.events` does not exist when the memory controller is not
+		// enabled, in which case we only watch `cgroup.events`.
+		if err != nil && !(name == "memory.events" && errors.Is(err, fs.ErrNotExist)) {
+			file.Close()
+			return nil, fmt.Errorf("inotify_add_watch %s: %w", name, err)
+		}
+	}
+
+	ooms := c.oomCount()
+	events := make(chan string)
+
+	go func() {
+		<-done
+		file.Close()
+	}()
+
+	go func() {
+		defer close(events)
+
+		buf := make([]byte, unix.SizeofInotifyEvent+unix.PathMax+1)
+		for {
+			if _, err := file.Read(buf); err != nil {
+				return
+			}
+
+			if n := c.oomCount(); n > ooms {
+				ooms = n
+
+				select {
+				case events <- EventOOM:
+				case <-done:
+					return
+				}
+			}
+
+			values, err := c.readKeyValues("cgroup.events")
+			if err != nil || values["populated"] == 0 {
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// oomCount returns the number of times the OOM killer has been invoked in the
+// cgroup, or 0 when the memory controller is not enabled.
+func (c *Cgroup) oomCount() uint64 {
+	values, err := c.readKeyValues("memory.events")
+	if err != nil {
+		return 0
+	}
+
+	return values["oom"]
+}
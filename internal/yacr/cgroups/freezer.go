@@ -0,0 +1,91 @@
+package cgroups
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Freeze freezes all the processes in the cgroup and waits until the cgroup
+// is frozen.
+//
+// See: https://docs.kernel.org/admin-guide/cgroup-v2.html#core-interface-files
+func (c *Cgroup) Freeze() error {
+	return c.setFrozen(true)
+}
+
+// Thaw thaws all the processes in the cgroup and waits until the cgroup is
+// not frozen anymore.
+func (c *Cgroup) Thaw() error {
+	return c.setFrozen(false)
+}
+
+func (c *Cgroup) setFrozen(frozen bool) error {
+	if !IsUnified() {
+		return ErrNotUnified
+	}
+
+	value := 0
+	if frozen {
+		value = 1
+	}
+
+	if err := c.write("cgroup.freeze", strconv.Itoa(value)); err != nil {
+		return fmt.Errorf("failed to write to cgroup.freeze: %w", err)
+	}
+
+	return c.waitFrozen(frozen)
+}
+
+// waitFrozen waits until the cgroup is (or is not) frozen.
+func (c *Cgroup) waitFrozen(frozen bool) error {
+	value := 0
+	if frozen {
+		value = 1
+	}
+
+	// The cgroup is frozen once all its processes have been stopped, which is
+	// reported (asynchronously) by the "frozen" key of `cgroup.events`.
+	for i := 0; i < 100; i++ {
+		events, err := c.readKeyValues("cgroup.events")
+		if err != nil {
+			return err
+		}
+
+		if events["frozen"] == uint64(value) {
+			return nil
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return fmt.Errorf("timed out waiting for cgroup '%s' (frozen = %d)", c.Path, value)
+}
+
+// readKeyValues reads a "flat keyed" interface file, i.e. a file with a
+// "<key> <value>" pair on each line.
+func (c *Cgroup) readKeyValues(name string) (map[string]uint64, error) {
+	data, err := os.ReadFile(filepath.Join(c.Path, name))
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]uint64)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		values[fields[0]] = v
+	}
+
+	return values, nil
+}
@@ -0,0 +1,71 @@
+package cgroups
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitFrozen(t *testing.T) {
+	cgroup := &Cgroup{Path: t.TempDir()}
+	events := filepath.Join(cgroup.Path, "cgroup.events")
+	if err := os.WriteFile(events, []byte("populated 1\nfrozen 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The kernel updates `cgroup.events` asynchronously.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		os.WriteFile(events, []byte("populated 1\nfrozen 1\n"), 0o644)
+	}()
+
+	if err := cgroup.waitFrozen(true); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+
+	if err := cgroup.waitFrozen(false); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected a timeout error, got: %v", err)
+	}
+}
+
+func TestWaitFrozenWithoutEvents(t *testing.T) {
+	cgroup := &Cgroup{Path: t.TempDir()}
+
+	if err := cgroup.waitFrozen(true); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a not exist error, got: %v", err)
+	}
+}
+
+func TestFreezeNotUnified(t *testing.T) {
+	if IsUnified() {
+		t.Skip("cgroup v2 is mounted")
+	}
+
+	cgroup := &Cgroup{Path: t.TempDir()}
+
+	if err := cgroup.Freeze(); !errors.Is(err, ErrNotUnified) {
+		t.Errorf("expected ErrNotUnified, got: %v", err)
+	}
+	if err := cgroup.Thaw(); !errors.Is(err, ErrNotUnified) {
+		t.Errorf("expected ErrNotUnified, got: %v", err)
+	}
+}
+
+func TestReadKeyValues(t *testing.T) {
+	cgroup := &Cgroup{Path: t.TempDir()}
+	if err := os.WriteFile(filepath.Join(cgroup.Path, "memory.events"), []byte("low 0\nmax 3\n\noom_kill 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := cgroup.readKeyValues("memory.events")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if len(values) != 3 || values["max"] != 3 || values["oom_kill"] != 1 {
+		t.Errorf("expected 3 values, got: %v", values)
+	}
+}
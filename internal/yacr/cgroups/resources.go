@@ -0,0 +1,322 @@
+package cgroups
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultCPUPeriod uint64 = 100000
+
+// Setting represents a value to write to a cgroup interface file.
+type Setting struct {
+	File  string
+	Value string
+}
+
+// Apply writes the resource limits to the cgroup interface files.
+func (c *Cgroup) Apply(resources *runtimespec.LinuxResources) error {
+	settings, err := ConvertResources(resources)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range settings {
+		logrus.WithFields(logrus.Fields{
+			"path":  c.Path,
+			"file":  s.File,
+			"value": s.Value,
+		}).Debug("apply cgroup setting")
+
+		if err := c.write(s.File, s.Value); err != nil {
+			return fmt.Errorf("failed to set %s: %w", s.File, err)
+		}
+	}
+
+	return nil
+}
+
+// Snapshot returns the current values of the interface files that `Apply()`
+// writes for the given resources so that they can be restored with
+// `Restore()`.
+func (c *Cgroup) Snapshot(resources *runtimespec.LinuxResources) ([]Setting, error) {
+	settings, err := ConvertResources(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot []Setting
+	seen := make(map[string]bool)
+	for _, s := range settings {
+		if seen[s.File] {
+			continue
+		}
+		seen[s.File] = true
+
+		data, err := os.ReadFile(filepath.Join(c.Path, s.File))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", s.File, err)
+		}
+		snapshot = append(snapshot, Setting{s.File, strings.TrimSpace(string(data))})
+	}
+
+	return snapshot, nil
+}
+
+// Restore writes the values returned by `Snapshot()` back to the interface
+// files, in reverse order. The files that contain one entry per line (e.g.
+// `io.max`) are written line by line. It returns the first error but attempts
+// to restore all the values.
+func (c *Cgroup) Restore(snapshot []Setting) error {
+	var firstErr error
+	for i := len(snapshot) - 1; i >= 0; i-- {
+		s := snapshot[i]
+		for _, line := range strings.Split(s.Value, "\n") {
+			if err := c.write(s.File, line); err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("failed to restore %s: %w", s.File, err)
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// HasLimits returns `true` when the resources define at least one limit that
+// is enforced with cgroups, and `false` otherwise.
+func HasLimits(resources *runtimespec.LinuxResources) bool {
+	settings, err := ConvertResources(resources)
+	return err != nil || len(settings) > 0
+}
+
+// ConvertResources converts the resources of a runtime configuration, which
+// have been designed for cgroup v1, into a list of cgroup v2 settings.
+//
+// Device rules are not taken into account because cgroup v2 requires an eBPF
+// program to enforce them.
+func ConvertResources(resources *runtimespec.LinuxResources) ([]Setting, error) {
+	var settings []Setting
+
+	if resources == nil {
+		return settings, nil
+	}
+
+	if m := resources.Memory; m != nil {
+		if m.Reservation != nil {
+			settings = append(settings, Setting{"memory.low", limitValue(*m.Reservation)})
+		}
+		if m.Limit != nil && *m.Limit != 0 {
+			settings = append(settings, Setting{"memory.max", limitValue(*m.Limit)})
+		}
+		if m.Swap != nil && *m.Swap != 0 {
+			swap, err := convertMemorySwap(*m.Swap, m.Limit)
+			if err != nil {
+				return settings, err
+			}
+			settings = append(settings, Setting{"memory.swap.max", limitValue(swap)})
+		}
+		if m.Kernel != nil || m.KernelTCP != nil {
+			return settings, fmt.Errorf("kernel memory limits are not supported with cgroup v2")
+		}
+		if m.Swappiness != nil {
+			return settings, fmt.Errorf("memory swappiness is not supported with cgroup v2")
+		}
+		if m.DisableOOMKiller != nil && *m.DisableOOMKiller {
+			return settings, fmt.Errorf("disabling the OOM killer is not supported with cgroup v2")
+		}
+		if m.CheckBeforeUpdate != nil && *m.CheckBeforeUpdate {
+			return settings, fmt.Errorf("checkBeforeUpdate is not supported")
+		}
+		if m.UseHierarchy != nil {
+			return settings, fmt.Errorf("memory useHierarchy is not supported with cgroup v2")
+		}
+	}
+
+	if cpu := resources.CPU; cpu != nil {
+		if cpu.Shares != nil && *cpu.Shares != 0 {
+			settings = append(settings, Setting{"cpu.weight", strconv.FormatUint(convertCPUShares(*cpu.Shares), 10)})
+		}
+		if (cpu.Quota != nil && *cpu.Quota != 0) || (cpu.Period != nil && *cpu.Period != 0) {
+			settings = append(settings, Setting{"cpu.max", cpuMaxValue(cpu.Quota, cpu.Period)})
+		}
+		if cpu.Burst != nil {
+			settings = append(settings, Setting{"cpu.max.burst", strconv.FormatUint(*cpu.Burst, 10)})
+		}
+		if cpu.Idle != nil {
+			settings = append(settings, Setting{"cpu.idle", strconv.FormatInt(*cpu.Idle, 10)})
+		}
+		if cpu.Cpus != "" {
+			settings = append(settings, Setting{"cpuset.cpus", cpu.Cpus})
+		}
+		if cpu.Mems != "" {
+			settings = append(settings, Setting{"cpuset.mems", cpu.Mems})
+		}
+		if cpu.RealtimeRuntime != nil || cpu.RealtimePeriod != nil {
+			return settings, fmt.Errorf("realtime CPU settings are not supported with cgroup v2")
+		}
+	}
+
+	if pids := resources.Pids; pids != nil && pids.Limit != 0 {
+		settings = append(settings, Setting{"pids.max", limitValue(pids.Limit)})
+	}
+
+	if bio := resources.BlockIO; bio != nil {
+		if bio.LeafWeight != nil {
+			return settings, fmt.Errorf("blockIO leafWeight is not supported with cgroup v2")
+		}
+		if bio.Weight != nil && *bio.Weight != 0 {
+			settings = append(settings, Setting{"io.weight", fmt.Sprintf("default %d", convertBlkioWeight(*bio.Weight))})
+		}
+		for _, d := range bio.WeightDevice {
+			if d.LeafWeight != nil {
+				return settings, fmt.Errorf("blockIO leafWeight is not supported with cgroup v2 (device %d:%d)", d.Major, d.Minor)
+			}
+			if d.Weight == nil {
+				continue
+			}
+			settings = append(settings, Setting{"io.weight", fmt.Sprintf("%d:%d %d", d.Major, d.Minor, convertBlkioWeight(*d.Weight))})
+		}
+		for _, t := range []struct {
+			key     string
+			devices []runtimespec.LinuxThrottleDevice
+		}{
+			{"rbps", bio.ThrottleReadBpsDevice},
+			{"wbps", bio.ThrottleWriteBpsDevice},
+			{"riops", bio.ThrottleReadIOPSDevice},
+			{"wiops", bio.ThrottleWriteIOPSDevice},
+		} {
+			for _, d := range t.devices {
+				rate := "max"
+				if d.Rate > 0 {
+					rate = strconv.FormatUint(d.Rate, 10)
+				}
+				settings = append(settings, Setting{"io.max", fmt.Sprintf("%d:%d %s=%s", d.Major, d.Minor, t.key, rate)})
+			}
+		}
+	}
+
+	for _, h := range resources.HugepageLimits {
+		if h.Pagesize == "" || strings.Contains(h.Pagesize, "/") {
+			return settings, fmt.Errorf("invalid hugepage size '%s'", h.Pagesize)
+		}
+		settings = append(settings, Setting{fmt.Sprintf("hugetlb.%s.max", h.Pagesize), strconv.FormatUint(h.Limit, 10)})
+	}
+
+	if resources.Network != nil {
+		return settings, fmt.Errorf("network limits are not supported with cgroup v2")
+	}
+
+	devices := make([]string, 0, len(resources.Rdma))
+	for device := range resources.Rdma {
+		devices = append(devices, device)
+	}
+	sort.Strings(devices)
+	for _, device := range devices {
+		rdma := resources.Rdma[device]
+		settings = append(settings, Setting{"rdma.max", fmt.Sprintf("%s hca_handle=%s hca_object=%s", device, rdmaLimitValue(rdma.HcaHandles), rdmaLimitValue(rdma.HcaObjects))})
+	}
+
+	// The unified settings are applied last so that they take precedence over
+	// the converted settings.
+	keys := make([]string, 0, len(resources.Unified))
+	for key := range resources.Unified {
+		if key == "" || strings.Contains(key, "/") || key == "." || key == ".." {
+			return settings, fmt.Errorf("invalid unified cgroup setting '%s'", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		settings = append(settings, Setting{key, resources.Unified[key]})
+	}
+
+	return settings, nil
+}
+
+// limitValue returns the cgroup v2 representation of a limit, i.e. "max" for
+// negative values (unlimited).
+func limitValue(value int64) string {
+	if value < 0 {
+		return "max"
+	}
+
+	return strconv.FormatInt(value, 10)
+}
+
+// rdmaLimitValue returns the value of an RDMA limit, i.e. "max" when there is
+// no limit.
+func rdmaLimitValue(value *uint32) string {
+	if value == nil {
+		return "max"
+	}
+
+	return strconv.FormatUint(uint64(*value), 10)
+}
+
+// convertMemorySwap converts the runtime-spec swap limit (memory + swap) into
+// the cgroup v2 value (swap only).
+func convertMemorySwap(swap int64, limit *int64) (int64, error) {
+	if swap == -1 {
+		return -1, nil
+	}
+
+	if limit == nil || *limit <= 0 {
+		return 0, fmt.Errorf("invalid memory swap limit %d: a memory limit is required", swap)
+	}
+
+	if swap < *limit {
+		return 0, fmt.Errorf("invalid memory swap limit %d: it must be greater than or equal to the memory limit (%d)", swap, *limit)
+	}
+
+	return swap - *limit, nil
+}
+
+// convertCPUShares converts CPU shares (cgroup v1: [2-262144]) into a CPU
+// weight (cgroup v2: [1-10000]).
+func convertCPUShares(shares uint64) uint64 {
+	if shares < 2 {
+		shares = 2
+	} else if shares > 262144 {
+		shares = 262144
+	}
+
+	return 1 + ((shares-2)*9999)/262142
+}
+
+// convertBlkioWeight converts a block IO weight (cgroup v1: [10-1000]) into
+// an IO weight (cgroup v2: [1-10000]).
+func convertBlkioWeight(weight uint16) uint64 {
+	w := uint64(weight)
+	if w < 10 {
+		w = 10
+	} else if w > 1000 {
+		w = 1000
+	}
+
+	return 1 + ((w-10)*9999)/990
+}
+
+// cpuMaxValue returns the value of `cpu.max`, which contains both the quota
+// and the period.
+func cpuMaxValue(quota *int64, period *uint64) string {
+	var b strings.Builder
+
+	if quota != nil && *quota > 0 {
+		b.WriteString(strconv.FormatInt(*quota, 10))
+	} else {
+		b.WriteString("max")
+	}
+
+	p := defaultCPUPeriod
+	if period != nil && *period != 0 {
+		p = *period
+	}
+	b.WriteString(" " + strconv.FormatUint(p, 10))
+
+	return b.String()
+}
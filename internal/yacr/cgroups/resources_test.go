@@ -0,0 +1,307 @@
+package cgroups
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestConvertResources(t *testing.T) {
+	limit := int64(1024 * 1024)
+	swap := int64(2 * 1024 * 1024)
+	shares := uint64(1024)
+	quota := int64(50000)
+	weight := uint16(500)
+
+	settings, err := ConvertResources(&runtimespec.LinuxResources{
+		Memory: &runtimespec.LinuxMemory{
+			Limit: &limit,
+			Swap:  &swap,
+		},
+		CPU: &runtimespec.LinuxCPU{
+			Shares: &shares,
+			Quota:  &quota,
+			Cpus:   "0-1",
+		},
+		Pids: &runtimespec.LinuxPids{
+			Limit: -1,
+		},
+		BlockIO: &runtimespec.LinuxBlockIO{
+			Weight: &weight,
+			ThrottleReadBpsDevice: []runtimespec.LinuxThrottleDevice{
+				{Rate: 1000},
+			},
+		},
+	})
+	if err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+
+	expected := []Setting{
+		{"memory.max", "1048576"},
+		{"memory.swap.max", "1048576"},
+		{"cpu.weight", "39"},
+		{"cpu.max", "50000 100000"},
+		{"cpuset.cpus", "0-1"},
+		{"pids.max", "max"},
+		{"io.weight", "default 4950"},
+		{"io.max", "0:0 rbps=1000"},
+	}
+
+	if len(settings) != len(expected) {
+		t.Fatalf("expected %d settings, got: %d", len(expected), len(settings))
+	}
+
+	for i, s := range expected {
+		if settings[i] != s {
+			t.Errorf("expected: %v, got: %v", s, settings[i])
+		}
+	}
+}
+
+func TestConvertResourcesUnified(t *testing.T) {
+	burst := uint64(1000)
+	idle := int64(1)
+
+	settings, err := ConvertResources(&runtimespec.LinuxResources{
+		Pids: &runtimespec.LinuxPids{
+			Limit: 10,
+		},
+		CPU: &runtimespec.LinuxCPU{
+			Burst: &burst,
+			Idle:  &idle,
+		},
+		Unified: map[string]string{
+			"pids.max":    "20",
+			"memory.high": "max",
+		},
+	})
+	if err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+
+	expected := []Setting{
+		{"cpu.max.burst", "1000"},
+		{"cpu.idle", "1"},
+		{"pids.max", "10"},
+		{"memory.high", "max"},
+		{"pids.max", "20"},
+	}
+
+	if len(settings) != len(expected) {
+		t.Fatalf("expected %d settings, got: %d", len(expected), len(settings))
+	}
+
+	for i, s := range expected {
+		if settings[i] != s {
+			t.Errorf("expected: %v, got: %v", s, settings[i])
+		}
+	}
+
+	if _, err := ConvertResources(&runtimespec.LinuxResources{
+		Unified: map[string]string{"../memory.max": "1"},
+	}); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestConvertResourcesHugepagesAndRdma(t *testing.T) {
+	handles := uint32(2)
+
+	settings, err := ConvertResources(&runtimespec.LinuxResources{
+		HugepageLimits: []runtimespec.LinuxHugepageLimit{
+			{Pagesize: "2MB", Limit: 4194304},
+		},
+		Rdma: map[string]runtimespec.LinuxRdma{
+			"mlx5_1": {},
+			"mlx4_0": {HcaHandles: &handles},
+		},
+	})
+	if err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+
+	expected := []Setting{
+		{"hugetlb.2MB.max", "4194304"},
+		{"rdma.max", "mlx4_0 hca_handle=2 hca_object=max"},
+		{"rdma.max", "mlx5_1 hca_handle=max hca_object=max"},
+	}
+
+	if len(settings) != len(expected) {
+		t.Fatalf("expected %d settings, got: %d", len(expected), len(settings))
+	}
+
+	for i, s := range expected {
+		if settings[i] != s {
+			t.Errorf("expected: %v, got: %v", s, settings[i])
+		}
+	}
+}
+
+func TestConvertResourcesUnsupported(t *testing.T) {
+	value := int64(1)
+	swappiness := uint64(0)
+	enabled := true
+	runtime := int64(1000)
+	weight := uint16(100)
+
+	for _, resources := range []*runtimespec.LinuxResources{
+		{Memory: &runtimespec.LinuxMemory{Kernel: &value}},
+		{Memory: &runtimespec.LinuxMemory{KernelTCP: &value}},
+		{Memory: &runtimespec.LinuxMemory{Swappiness: &swappiness}},
+		{Memory: &runtimespec.LinuxMemory{DisableOOMKiller: &enabled}},
+		{Memory: &runtimespec.LinuxMemory{CheckBeforeUpdate: &enabled}},
+		{Memory: &runtimespec.LinuxMemory{UseHierarchy: &enabled}},
+		{BlockIO: &runtimespec.LinuxBlockIO{LeafWeight: &weight}},
+		{BlockIO: &runtimespec.LinuxBlockIO{WeightDevice: []runtimespec.LinuxWeightDevice{{LeafWeight: &weight}}}},
+		{CPU: &runtimespec.LinuxCPU{RealtimeRuntime: &runtime}},
+		{Network: &runtimespec.LinuxNetwork{}},
+		{HugepageLimits: []runtimespec.LinuxHugepageLimit{{Pagesize: "../2MB"}}},
+	} {
+		if _, err := ConvertResources(resources); err == nil {
+			t.Errorf("expected an error for: %+v", resources)
+		}
+	}
+}
+
+func TestConvertResourcesNil(t *testing.T) {
+	settings, err := ConvertResources(nil)
+	if err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+
+	if len(settings) != 0 {
+		t.Errorf("expected %d settings, got: %d", 0, len(settings))
+	}
+}
+
+func TestConvertResourcesInvalidSwap(t *testing.T) {
+	limit := int64(2048)
+	swap := int64(1024)
+
+	for _, m := range []*runtimespec.LinuxMemory{
+		// No memory limit.
+		{Swap: &swap},
+		// Swap lower than the memory limit.
+		{Limit: &limit, Swap: &swap},
+	} {
+		if _, err := ConvertResources(&runtimespec.LinuxResources{Memory: m}); err == nil {
+			t.Errorf("expected an error for memory: %+v", m)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, tc := range []struct {
+		cgroupsPath string
+		expected    string
+	}{
+		{cgroupsPath: "", expected: "/sys/fs/cgroup/yacr/some-id"},
+		{cgroupsPath: "/default/some-id", expected: "/sys/fs/cgroup/default/some-id"},
+		{cgroupsPath: "relative/path", expected: "/sys/fs/cgroup/relative/path"},
+		{cgroupsPath: "/../../etc", expected: "/sys/fs/cgroup/etc"},
+	} {
+		cgroup, err := New(tc.cgroupsPath, "some-id")
+		if err != nil {
+			t.Errorf("expected no error, got: %v", err)
+			continue
+		}
+
+		if cgroup.Path != tc.expected {
+			t.Errorf("expected: %s, got: %s", tc.expected, cgroup.Path)
+		}
+	}
+
+	for _, cgroupsPath := range []string{"/", "system.slice:yacr:some-id"} {
+		if _, err := New(cgroupsPath, "some-id"); err == nil {
+			t.Errorf("expected an error for: %s", cgroupsPath)
+		}
+	}
+}
+
+func TestConvertResourcesReset(t *testing.T) {
+	unlimited := int64(-1)
+	zero := int64(0)
+
+	settings, err := ConvertResources(&runtimespec.LinuxResources{
+		Memory: &runtimespec.LinuxMemory{Limit: &unlimited, Reservation: &zero, Swap: &unlimited},
+		CPU:    &runtimespec.LinuxCPU{Quota: &unlimited},
+		Pids:   &runtimespec.LinuxPids{Limit: -1},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	expected := []Setting{
+		{"memory.low", "0"},
+		{"memory.max", "max"},
+		{"memory.swap.max", "max"},
+		{"cpu.max", "max 100000"},
+		{"pids.max", "max"},
+	}
+
+	if len(settings) != len(expected) {
+		t.Fatalf("expected %d settings, got: %v", len(expected), settings)
+	}
+
+	for i, s := range expected {
+		if settings[i] != s {
+			t.Errorf("expected: %v, got: %v", s, settings[i])
+		}
+	}
+}
+
+func TestSnapshotAndRestore(t *testing.T) {
+	cgroup := &Cgroup{Path: t.TempDir()}
+	for name, content := range map[string]string{
+		"memory.max": "max\n",
+		"pids.max":   "10\n",
+	} {
+		if err := os.WriteFile(filepath.Join(cgroup.Path, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	limit := int64(1024)
+	resources := &runtimespec.LinuxResources{
+		Memory: &runtimespec.LinuxMemory{Limit: &limit},
+		Pids:   &runtimespec.LinuxPids{Limit: 20},
+	}
+
+	snapshot, err := cgroup.Snapshot(resources)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if err := cgroup.Apply(resources); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if err := cgroup.Restore(snapshot); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	for name, expected := range map[string]string{
+		"memory.max": "max",
+		"pids.max":   "10",
+	} {
+		data, err := os.ReadFile(filepath.Join(cgroup.Path, name))
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		if string(data) != expected {
+			t.Errorf("expected %s: %q, got: %q", name, expected, data)
+		}
+	}
+}
+
+func TestSnapshotMissingFile(t *testing.T) {
+	cgroup := &Cgroup{Path: t.TempDir()}
+
+	if _, err := cgroup.Snapshot(&runtimespec.LinuxResources{Pids: &runtimespec.LinuxPids{Limit: 20}}); err == nil {
+		t.Errorf("expected an error")
+	}
+}
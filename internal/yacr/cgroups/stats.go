@@ -0,0 +1,236 @@
+package cgroups
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Stats contains the statistics of a cgroup. The JSON representation is
+// compatible with the one used by runc.
+//
+// See: https://github.com/opencontainers/runc/blob/main/types/events.go
+type Stats struct {
+	CPU    CPU    `json:"cpu"`
+	Memory Memory `json:"memory"`
+	Pids   Pids   `json:"pids"`
+	Blkio  Blkio  `json:"blkio"`
+}
+
+type CPU struct {
+	Usage      CPUUsage   `json:"usage,omitempty"`
+	Throttling Throttling `json:"throttling,omitempty"`
+}
+
+// CPUUsage contains the CPU usage in nanoseconds.
+type CPUUsage struct {
+	Total  uint64 `json:"total,omitempty"`
+	Kernel uint64 `json:"kernel"`
+	User   uint64 `json:"user"`
+}
+
+type Throttling struct {
+	Periods          uint64 `json:"periods,omitempty"`
+	ThrottledPeriods uint64 `json:"throttledPeriods,omitempty"`
+	ThrottledTime    uint64 `json:"throttledTime,omitempty"`
+}
+
+type Memory struct {
+	Cache uint64            `json:"cache,omitempty"`
+	Usage MemoryEntry       `json:"usage,omitempty"`
+	Swap  MemoryEntry       `json:"swap,omitempty"`
+	Raw   map[string]uint64 `json:"raw,omitempty"`
+}
+
+type MemoryEntry struct {
+	Limit   uint64 `json:"limit"`
+	Usage   uint64 `json:"usage,omitempty"`
+	Max     uint64 `json:"max,omitempty"`
+	Failcnt uint64 `json:"failcnt"`
+}
+
+type Pids struct {
+	Current uint64 `json:"current,omitempty"`
+	Limit   uint64 `json:"limit,omitempty"`
+}
+
+type Blkio struct {
+	IoServiceBytesRecursive []BlkioEntry `json:"ioServiceBytesRecursive,omitempty"`
+	IoServicedRecursive     []BlkioEntry `json:"ioServicedRecursive,omitempty"`
+}
+
+type BlkioEntry struct {
+	Major uint64 `json:"major,omitempty"`
+	Minor uint64 `json:"minor,omitempty"`
+	Op    string `json:"op,omitempty"`
+	Value uint64 `json:"value,omitempty"`
+}
+
+// Stats returns the statistics of the cgroup. The interface files of the
+// controllers that are not enabled are ignored.
+func (c *Cgroup) Stats() (*Stats, error) {
+	stats := &Stats{}
+
+	for _, f := range []func(*Stats) error{
+		c.cpuStats,
+		c.memoryStats,
+		c.pidsStats,
+		c.ioStats,
+	} {
+		if err := f(stats); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	return stats, nil
+}
+
+func (c *Cgroup) cpuStats(stats *Stats) error {
+	values, err := c.readKeyValues("cpu.stat")
+	if err != nil {
+		return err
+	}
+
+	// Values are in microseconds.
+	stats.CPU.Usage.Total = values["usage_usec"] * 1000
+	stats.CPU.Usage.User = values["user_usec"] * 1000
+	stats.CPU.Usage.Kernel = values["system_usec"] * 1000
+	stats.CPU.Throttling.Periods = values["nr_periods"]
+	stats.CPU.Throttling.ThrottledPeriods = values["nr_throttled"]
+	stats.CPU.Throttling.ThrottledTime = values["throttled_usec"] * 1000
+
+	return nil
+}
+
+func (c *Cgroup) memoryStats(stats *Stats) error {
+	raw, err := c.readKeyValues("memory.stat")
+	if err != nil {
+		return err
+	}
+	stats.Memory.Raw = raw
+	stats.Memory.Cache = raw["file"]
+
+	if stats.Memory.Usage.Usage, err = c.readUint("memory.current"); err != nil {
+		return err
+	}
+	if stats.Memory.Usage.Limit, err = c.readUint("memory.max"); err != nil {
+		return err
+	}
+	// `memory.peak` is only available since Linux 5.19.
+	if peak, err := c.readUint("memory.peak"); err == nil {
+		stats.Memory.Usage.Max = peak
+	}
+
+	// The "max" event is the number of times the usage was about to go over
+	// the limit, which is the closest thing to the cgroup v1 failcnt.
+	events, err := c.readKeyValues("memory.events")
+	if err != nil {
+		return err
+	}
+	stats.Memory.Usage.Failcnt = events["max"]
+
+	// Swap files do not exist when swap accounting is disabled.
+	if usage, err := c.readUint("memory.swap.current"); err == nil {
+		stats.Memory.Swap.Usage = usage
+	}
+	if limit, err := c.readUint("memory.swap.max"); err == nil {
+		stats.Memory.Swap.Limit = limit
+	}
+
+	return nil
+}
+
+func (c *Cgroup) pidsStats(stats *Stats) error {
+	current, err := c.readUint("pids.current")
+	if err != nil {
+		return err
+	}
+	stats.Pids.Current = current
+
+	limit, err := c.readUint("pids.max")
+	if err != nil {
+		return err
+	}
+	// Like runc, we report 0 when there is no limit.
+	if limit != math.MaxUint64 {
+		stats.Pids.Limit = limit
+	}
+
+	return nil
+}
+
+func (c *Cgroup) ioStats(stats *Stats) error {
+	data, err := os.ReadFile(filepath.Join(c.Path, "io.stat"))
+	if err != nil {
+		return err
+	}
+
+	// Each line looks like: "8:0 rbytes=1024 wbytes=0 rios=1 wios=0 [...]".
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		var major, minor uint64
+		if _, err := fmt.Sscanf(fields[0], "%d:%d", &major, &minor); err != nil {
+			return fmt.Errorf("failed to parse io.stat: %w", err)
+		}
+
+		for _, kv := range fields[1:] {
+			key, value, ok := strings.Cut(kv, "=")
+			if !ok {
+				continue
+			}
+
+			v, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("failed to parse io.stat: %w", err)
+			}
+
+			entry := BlkioEntry{Major: major, Minor: minor, Value: v}
+			switch key {
+			case "rbytes":
+				entry.Op = "Read"
+				stats.Blkio.IoServiceBytesRecursive = append(stats.Blkio.IoServiceBytesRecursive, entry)
+			case "wbytes":
+				entry.Op = "Write"
+				stats.Blkio.IoServiceBytesRecursive = append(stats.Blkio.IoServiceBytesRecursive, entry)
+			case "rios":
+				entry.Op = "Read"
+				stats.Blkio.IoServicedRecursive = append(stats.Blkio.IoServicedRecursive, entry)
+			case "wios":
+				entry.Op = "Write"
+				stats.Blkio.IoServicedRecursive = append(stats.Blkio.IoServicedRecursive, entry)
+			}
+		}
+	}
+
+	return nil
+}
+
+// readUint reads an interface file that contains a single value. The special
+// "max" value is converted to `math.MaxUint64`.
+func (c *Cgroup) readUint(name string) (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(c.Path, name))
+	if err != nil {
+		return 0, err
+	}
+
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return math.MaxUint64, nil
+	}
+
+	v, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	return v, nil
+}
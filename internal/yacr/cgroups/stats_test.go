@@ -0,0 +1,70 @@
+package cgroups
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"cpu.stat":       "usage_usec 100\nuser_usec 60\nsystem_usec 40\nnr_periods 5\nnr_throttled 2\nthrottled_usec 10\n",
+		"memory.stat":    "anon 4096\nfile 8192\n",
+		"memory.current": "12288\n",
+		"memory.max":     "max\n",
+		"memory.events":  "low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\n",
+		"pids.current":   "2\n",
+		"pids.max":       "max\n",
+		"io.stat":        "8:0 rbytes=1024 wbytes=2048 rios=1 wios=2 dbytes=0 dios=0\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cgroup := &Cgroup{Path: dir}
+	stats, err := cgroup.Stats()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	expected := &Stats{
+		CPU: CPU{
+			Usage:      CPUUsage{Total: 100000, User: 60000, Kernel: 40000},
+			Throttling: Throttling{Periods: 5, ThrottledPeriods: 2, ThrottledTime: 10000},
+		},
+		Memory: Memory{
+			Cache: 8192,
+			Usage: MemoryEntry{Usage: 12288, Limit: 1<<64 - 1, Failcnt: 3},
+			Raw:   map[string]uint64{"anon": 4096, "file": 8192},
+		},
+		Pids: Pids{Current: 2},
+		Blkio: Blkio{
+			IoServiceBytesRecursive: []BlkioEntry{
+				{Major: 8, Minor: 0, Op: "Read", Value: 1024},
+				{Major: 8, Minor: 0, Op: "Write", Value: 2048},
+			},
+			IoServicedRecursive: []BlkioEntry{
+				{Major: 8, Minor: 0, Op: "Read", Value: 1},
+				{Major: 8, Minor: 0, Op: "Write", Value: 2},
+			},
+		},
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("expected: %+v, got: %+v", expected, stats)
+	}
+}
+
+func TestStatsWithoutControllers(t *testing.T) {
+	cgroup := &Cgroup{Path: t.TempDir()}
+	stats, err := cgroup.Stats()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if !reflect.DeepEqual(stats, &Stats{}) {
+		t.Errorf("expected empty stats, got: %+v", stats)
+	}
+}
@@ -0,0 +1,135 @@
+package yacr
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+
+	"github.com/creack/pty"
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/sirupsen/logrus"
+	"github.com/willdurand/containers/thirdparty/runc/libcontainer/utils"
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// consoleSize returns the initial size of the terminal of a process, or `nil`
+// when it is not specified.
+func consoleSize(process *runtimespec.Process) *pty.Winsize {
+	if process.ConsoleSize == nil {
+		return nil
+	}
+
+	return &pty.Winsize{
+		Rows: uint16(process.ConsoleSize.Height),
+		Cols: uint16(process.ConsoleSize.Width),
+	}
+}
+
+// sendConsole sends the master side of a PTY to the console socket.
+//
+// See: https://github.com/opencontainers/runc/blob/016a0d29d1750180b2a619fc70d6fe0d80111be0/docs/terminals.md#detached-new-terminal
+func sendConsole(consoleSocket string, ptm *os.File) error {
+	// Connect to the socket in order to send the PTY file descriptor.
+	conn, err := net.Dial("unix", consoleSocket)
+	if err != nil {
+		return fmt.Errorf("failed to dial console socket: %w", err)
+	}
+	defer conn.Close()
+
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return errors.New("failed to cast unix socket")
+	}
+	defer uc.Close()
+
+	// Send file descriptor over socket.
+	oob := unix.UnixRights(int(ptm.Fd()))
+	if _, _, err := uc.WriteMsgUnix([]byte(ptm.Name()), oob, nil); err != nil {
+		return fmt.Errorf("failed to send pty: %w", err)
+	}
+
+	return nil
+}
+
+// receiveConsole accepts a connection on a console socket and returns the
+// master side of the PTY sent by `sendConsole()`.
+func receiveConsole(ln net.Listener) (*os.File, error) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept console socket connection: %w", err)
+	}
+	defer conn.Close()
+
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil, errors.New("failed to cast unix socket")
+	}
+
+	socket, err := uc.File()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve console socket: %w", err)
+	}
+	defer socket.Close()
+
+	ptm, err := utils.RecvFd(socket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive pty: %w", err)
+	}
+
+	return ptm, nil
+}
+
+// attachConsole connects the standard streams of the current process to the
+// master side of a PTY. When the standard input is a terminal, it is switched
+// to "raw mode" and its size is propagated to the PTY.
+//
+// The returned function waits until the output of the PTY has been copied and
+// restores the terminal. It should be called once the process attached to the
+// PTY has exited.
+func attachConsole(ptm *os.File) (func(), error) {
+	stdin := int(os.Stdin.Fd())
+	restore := func() {}
+
+	if term.IsTerminal(stdin) {
+		oldState, err := term.MakeRaw(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set terminal in raw mode: %w", err)
+		}
+
+		resize := make(chan os.Signal, 1)
+		signal.Notify(resize, unix.SIGWINCH)
+		resize <- unix.SIGWINCH
+
+		go func() {
+			for range resize {
+				if err := pty.InheritSize(os.Stdin, ptm); err != nil {
+					logrus.WithError(err).Debug("failed to resize pty")
+				}
+			}
+		}()
+
+		restore = func() {
+			signal.Stop(resize)
+			term.Restore(stdin, oldState)
+		}
+	}
+
+	go io.Copy(ptm, os.Stdin)
+
+	// Reading from the PTY fails once the process and its descendants have
+	// closed the other side.
+	done := make(chan interface{})
+	go func() {
+		io.Copy(os.Stdout, ptm)
+		close(done)
+	}()
+
+	return func() {
+		<-done
+		restore()
+	}, nil
+}
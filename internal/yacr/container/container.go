@@ -3,14 +3,21 @@ package container
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
 
 	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/sirupsen/logrus"
 	"github.com/willdurand/containers/internal/runtime"
+	"github.com/willdurand/containers/internal/yacr/cgroups"
 	"github.com/willdurand/containers/internal/yacr/ipc"
 )
 
@@ -28,9 +35,18 @@ func Load(rootDir string, id string) (*YacrContainer, error) {
 	return &YacrContainer{base}, err
 }
 
+// resourcesFileName is the name of the file that contains the resources of a
+// container when they have been changed with `yacr update`.
+const resourcesFileName = "resources.json"
+
 func LoadWithBundleConfig(rootDir string, id string) (*YacrContainer, error) {
 	base, err := runtime.LoadWithBundleConfig(rootDir, id)
-	return &YacrContainer{base}, err
+	container := &YacrContainer{base}
+	if err != nil {
+		return container, err
+	}
+
+	return container, container.LoadResources()
 }
 
 func LoadFromContainer(BaseDir string, id string) (*YacrContainer, error) {
@@ -64,19 +80,102 @@ func (c *YacrContainer) GetSockAddr(mustExist bool) (string, error) {
 	return sockAddr, ipc.EnsureValidSockAddr(sockAddr, mustExist)
 }
 
+// SaveResources persists the resources of the container, which take precedence
+// over the resources defined in the bundle configuration.
+func (c *YacrContainer) SaveResources(resources *runtimespec.LinuxResources) error {
+	data, err := json.Marshal(resources)
+	if err != nil {
+		return fmt.Errorf("failed to serialize resources: %w", err)
+	}
+
+	if err := runtime.WriteFileAtomic(filepath.Join(c.BaseDir, resourcesFileName), data, 0o644); err != nil {
+		return fmt.Errorf("failed to save resources: %w", err)
+	}
+
+	if c.Spec.Linux == nil {
+		c.Spec.Linux = new(runtimespec.Linux)
+	}
+	c.Spec.Linux.Resources = resources
+
+	return nil
+}
+
+// LoadResources replaces the resources of the bundle configuration with the
+// ones saved by `SaveResources()`, if any.
+func (c *YacrContainer) LoadResources() error {
+	data, err := os.ReadFile(filepath.Join(c.BaseDir, resourcesFileName))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read resources: %w", err)
+	}
+
+	resources := new(runtimespec.LinuxResources)
+	if err := json.Unmarshal(data, resources); err != nil {
+		return fmt.Errorf("failed to parse resources: %w", err)
+	}
+
+	if c.Spec.Linux == nil {
+		c.Spec.Linux = new(runtimespec.Linux)
+	}
+	c.Spec.Linux.Resources = resources
+
+	return nil
+}
+
+// Cgroup returns the cgroup of the container.
+func (c *YacrContainer) Cgroup() (*cgroups.Cgroup, error) {
+	cgroupsPath := ""
+	if c.Spec.Linux != nil {
+		cgroupsPath = c.Spec.Linux.CgroupsPath
+	}
+
+	return cgroups.New(cgroupsPath, c.ID())
+}
+
+// hooksByName returns the hooks of a runtime configuration indexed by name.
+func hooksByName(hooks *runtimespec.Hooks) map[string][]runtimespec.Hook {
+	return map[string][]runtimespec.Hook{
+		"prestart":        hooks.Prestart,
+		"createRuntime":   hooks.CreateRuntime,
+		"createContainer": hooks.CreateContainer,
+		"startContainer":  hooks.StartContainer,
+		"poststart":       hooks.Poststart,
+		"poststop":        hooks.Poststop,
+	}
+}
+
+// SupportedHooks returns the names of the hooks supported by yacr.
+func SupportedHooks() []string {
+	var names []string
+	for name := range hooksByName(&runtimespec.Hooks{}) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// warnOnlyHooks contains the names of the hooks whose failures must only be
+// logged as warnings. The remaining hooks are still executed and the lifecycle
+// continues.
+//
+// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/runtime.md#lifecycle
+var warnOnlyHooks = map[string]bool{
+	"poststart": true,
+	"poststop":  true,
+}
+
+// ExecuteHooks executes the hooks of a given type in order. It returns an error
+// on the first hook that fails, except for the `poststart` and `poststop`
+// hooks, for which a warning is logged instead.
 func (c *YacrContainer) ExecuteHooks(name string) error {
 	if c.Spec.Hooks == nil {
 		return nil
 	}
 
-	hooks := map[string][]runtimespec.Hook{
-		"Prestart":        c.Spec.Hooks.Prestart,
-		"CreateRuntime":   c.Spec.Hooks.CreateRuntime,
-		"CreateContainer": c.Spec.Hooks.CreateContainer,
-		"StartContainer":  c.Spec.Hooks.StartContainer,
-		"Poststart":       c.Spec.Hooks.Poststart,
-		"Poststop":        c.Spec.Hooks.Poststop,
-	}[name]
+	hooks := hooksByName(c.Spec.Hooks)[name]
 
 	if len(hooks) == 0 {
 		logrus.WithFields(logrus.Fields{
@@ -102,23 +201,37 @@ func (c *YacrContainer) ExecuteHooks(name string) error {
 	for _, hook := range hooks {
 		var stdout, stderr bytes.Buffer
 
-		cmd := exec.Cmd{
+		cmd := &exec.Cmd{
 			Path:   hook.Path,
 			Args:   hook.Args,
 			Env:    hook.Env,
 			Stdin:  bytes.NewReader(s),
 			Stdout: &stdout,
 			Stderr: &stderr,
+			// The hook gets its own process group so that we can kill it along with
+			// its descendants when it times out.
+			SysProcAttr: &syscall.SysProcAttr{Setpgid: true},
 		}
 
-		if err := cmd.Run(); err != nil {
-			logrus.WithFields(logrus.Fields{
+		if err := runHook(cmd, hook.Timeout); err != nil {
+			entry := logrus.WithFields(logrus.Fields{
 				"id":     c.ID(),
 				"name:":  name,
 				"error":  err,
 				"stderr": stderr.String(),
 				"stdout": stdout.String(),
-			}).Error("failed to execute hooks")
+			})
+
+			if warnOnlyHooks[name] {
+				entry.Warn("failed to execute hook")
+				continue
+			}
+
+			entry.Error("failed to execute hooks")
+
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return fmt.Errorf("failed to execute %s hook '%s': %w (stderr: %s)", name, cmd.String(), err, msg)
+			}
 
 			return fmt.Errorf("failed to execute %s hook '%s': %w", name, cmd.String(), err)
 		}
@@ -126,3 +239,78 @@ func (c *YacrContainer) ExecuteHooks(name string) error {
 
 	return nil
 }
+
+// cgroupFileName is the name of the file created when yacr has created the
+// cgroup of a container, as opposed to an existing cgroup given in the runtime
+// configuration. Only the former is destroyed with the container.
+const cgroupFileName = "cgroup"
+
+// SaveCgroupCreated records that yacr has created the cgroup of the container.
+func (c *YacrContainer) SaveCgroupCreated() error {
+	if err := os.WriteFile(filepath.Join(c.BaseDir, cgroupFileName), nil, 0o644); err != nil {
+		return fmt.Errorf("failed to save cgroup creation: %w", err)
+	}
+
+	return nil
+}
+
+// CgroupCreated returns `true` when yacr has created the cgroup of the
+// container (see `SaveCgroupCreated()`).
+func (c *YacrContainer) CgroupCreated() bool {
+	_, err := os.Stat(filepath.Join(c.BaseDir, cgroupFileName))
+	return err == nil
+}
+
+// poststopFileName is the name of the file created once the `poststop` hooks
+// of a container have been executed.
+const poststopFileName = "poststop"
+
+// ExecutePoststopHooks executes the `poststop` hooks of the container unless
+// they have already been executed, e.g. by `yacr run --keep`.
+func (c *YacrContainer) ExecutePoststopHooks() error {
+	marker := filepath.Join(c.BaseDir, poststopFileName)
+	if _, err := os.Stat(marker); err == nil {
+		logrus.WithField("id", c.ID()).Debug("poststop hooks already executed")
+		return nil
+	}
+
+	if err := c.ExecuteHooks("poststop"); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(marker, nil, 0o644); err != nil {
+		return fmt.Errorf("failed to save poststop hooks execution: %w", err)
+	}
+
+	return nil
+}
+
+// runHook executes the command of a hook and waits for it. When a timeout (in
+// seconds) is given, the process group of the hook is killed once the timeout
+// has expired.
+func runHook(cmd *exec.Cmd, timeout *int) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	if timeout == nil {
+		return <-done
+	}
+
+	timer := time.NewTimer(time.Duration(*timeout) * time.Second)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		return fmt.Errorf("timed out after %ds", *timeout)
+	}
+}
@@ -0,0 +1,136 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/willdurand/containers/internal/runtime"
+)
+
+func newTestContainer(hooks *runtimespec.Hooks) *YacrContainer {
+	return &YacrContainer{
+		BaseContainer: &runtime.BaseContainer{
+			Spec:  runtimespec.Spec{Hooks: hooks},
+			State: runtimespec.State{ID: "test-id", Status: "creating"},
+		},
+	}
+}
+
+func TestExecuteHooksTimeout(t *testing.T) {
+	timeout := 1
+	c := newTestContainer(&runtimespec.Hooks{
+		CreateRuntime: []runtimespec.Hook{
+			{Path: "/bin/sh", Args: []string{"sh", "-c", "sleep 10 & sleep 10"}, Timeout: &timeout},
+		},
+	})
+
+	start := time.Now()
+	err := c.ExecuteHooks("createRuntime")
+
+	if err == nil || !strings.Contains(err.Error(), "timed out after 1s") {
+		t.Errorf("expected a timeout error, got: %v", err)
+	}
+
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("expected hook to be killed after 1s, took: %s", d)
+	}
+}
+
+func TestExecuteHooksStderr(t *testing.T) {
+	c := newTestContainer(&runtimespec.Hooks{
+		CreateContainer: []runtimespec.Hook{
+			{Path: "/bin/sh", Args: []string{"sh", "-c", "grep -q '\"id\":\"test-id\"' && echo oops >&2; exit 1"}},
+		},
+	})
+
+	err := c.ExecuteHooks("createContainer")
+
+	if err == nil || !strings.HasSuffix(err.Error(), "exit status 1 (stderr: oops)") {
+		t.Errorf("expected an error with stderr, got: %v", err)
+	}
+}
+
+func TestExecuteHooksFailFast(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "counter")
+	failing := []runtimespec.Hook{
+		{Path: "/bin/sh", Args: []string{"sh", "-c", "exit 1"}},
+		{Path: "/bin/sh", Args: []string{"sh", "-c", "echo x >> " + counter}},
+	}
+
+	c := newTestContainer(&runtimespec.Hooks{
+		CreateRuntime:   failing,
+		CreateContainer: failing,
+		StartContainer:  failing,
+		Poststart:       failing,
+		Poststop:        failing,
+	})
+
+	for _, name := range []string{"createRuntime", "createContainer", "startContainer"} {
+		if err := c.ExecuteHooks(name); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	// The remaining hooks are executed when a poststart or poststop hook fails.
+	for _, name := range []string{"poststart", "poststop"} {
+		if err := c.ExecuteHooks(name); err != nil {
+			t.Errorf("%s: expected no error, got: %v", name, err)
+		}
+	}
+
+	data, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if string(data) != "x\nx\n" {
+		t.Errorf("expected the hooks after the failing ones to be executed twice, got: %q", data)
+	}
+}
+
+func TestExecutePoststopHooksOnce(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "counter")
+
+	c := newTestContainer(&runtimespec.Hooks{
+		Poststop: []runtimespec.Hook{
+			{Path: "/bin/sh", Args: []string{"sh", "-c", "echo x >> " + counter}},
+		},
+	})
+	c.BaseDir = t.TempDir()
+
+	for i := 0; i < 2; i++ {
+		if err := c.ExecutePoststopHooks(); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if string(data) != "x\n" {
+		t.Errorf("expected hooks to be executed once, got: %q", data)
+	}
+}
+
+func TestCgroupCreated(t *testing.T) {
+	c := newTestContainer(nil)
+	c.BaseDir = t.TempDir()
+
+	if c.CgroupCreated() {
+		t.Errorf("expected cgroup not to be created")
+	}
+
+	if err := c.SaveCgroupCreated(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if !c.CgroupCreated() {
+		t.Errorf("expected cgroup to be created")
+	}
+}
@@ -1,13 +1,13 @@
 package yacr
 
 import (
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"os"
 	"os/exec"
 	"strconv"
+	"strings"
 	"syscall"
 
 	"github.com/creack/pty"
@@ -16,6 +16,7 @@ import (
 	"github.com/willdurand/containers/internal/cmd"
 	"github.com/willdurand/containers/internal/yacr/container"
 	"github.com/willdurand/containers/internal/yacr/ipc"
+	"github.com/willdurand/containers/internal/yacr/nsenter"
 	"golang.org/x/sys/unix"
 )
 
@@ -40,7 +41,9 @@ func Create(rootDir string, opts CreateOpts) error {
 		return err
 	}
 
-	// TODO: error when there is no linux configuration
+	if err := validateSpec(container.Spec); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
 
 	if err := container.Save(); err != nil {
 		return err
@@ -81,44 +84,85 @@ func Create(rootDir string, opts CreateOpts) error {
 		containerArgs = append([]string{"--debug"}, containerArgs...)
 	}
 	if opts.NoPivot {
-		containerArgs = append([]string{"--no-pivot"}, containerArgs...)
+		containerArgs = append(containerArgs, "--no-pivot")
 	}
 
 	var cloneFlags uintptr
-	for _, ns := range container.Spec.Linux.Namespaces {
-		switch ns.Type {
-		case runtimespec.UTSNamespace:
-			cloneFlags |= syscall.CLONE_NEWUTS
-		case runtimespec.PIDNamespace:
-			cloneFlags |= syscall.CLONE_NEWPID
-		case runtimespec.MountNamespace:
-			cloneFlags |= syscall.CLONE_NEWNS
-		case runtimespec.UserNamespace:
-			cloneFlags |= syscall.CLONE_NEWUSER
-		case runtimespec.NetworkNamespace:
-			cloneFlags |= syscall.CLONE_NEWNET
-		case runtimespec.IPCNamespace:
-			cloneFlags |= syscall.CLONE_NEWIPC
-		case runtimespec.CgroupNamespace:
-			logrus.Info("skipping cgroup namespace")
-		default:
-			return fmt.Errorf("unsupported namespace: %s", ns.Type)
+	// Some namespaces cannot be created with clone(2), e.g. the time namespace.
+	// These are created by the container process (see the `nsenter` package).
+	var unshareFlags uintptr
+	// Existing namespaces are joined by the host before the container process
+	// is created, except the user, mount and time namespaces, which must be
+	// joined by the container process itself (see the `nsenter` package).
+	var hostNamespaces []runtimespec.LinuxNamespace
+	var containerNamespaces []string
+	var joinUserNamespace bool
+	for _, f := range namespaceFiles {
+		for _, ns := range container.Spec.Linux.Namespaces {
+			if ns.Type != f.nsType {
+				continue
+			}
+
+			if ns.Path != "" {
+				if _, err := os.Stat(ns.Path); err != nil {
+					return fmt.Errorf("invalid %s namespace: %w", ns.Type, err)
+				}
+			}
+
+			switch {
+			case ns.Path != "" && (ns.Type == runtimespec.UserNamespace || ns.Type == runtimespec.MountNamespace || ns.Type == runtimespec.TimeNamespace):
+				containerNamespaces = append(containerNamespaces, fmt.Sprintf("%s:%s", f.name, ns.Path))
+				joinUserNamespace = joinUserNamespace || ns.Type == runtimespec.UserNamespace
+			case ns.Path != "":
+				hostNamespaces = append(hostNamespaces, ns)
+			case ns.Type == runtimespec.CgroupNamespace:
+				// The cgroup namespace is created by the container process once it has
+				// been moved into its cgroup, see `CreateContainer()`.
+			case ns.Type == runtimespec.TimeNamespace:
+				unshareFlags |= f.flag
+			default:
+				cloneFlags |= f.flag
+			}
 		}
 	}
 
 	env := os.Environ()
-	if cloneFlags&syscall.CLONE_NEWUSER != syscall.CLONE_NEWUSER {
+	newUserNamespace := cloneFlags&syscall.CLONE_NEWUSER == syscall.CLONE_NEWUSER
+	if !newUserNamespace {
 		// When we don't have a user namespace, there is no need to re-exec because
 		// we won't configure the uid/gid maps.
 		env = append(env, "_YACR_CONTAINER_REEXEC=1")
 	}
 
-	self, err := os.Executable()
+	// The new namespaces must be owned by the joined user namespace so they are
+	// created by the container process after it has joined it.
+	if joinUserNamespace {
+		unshareFlags |= cloneFlags
+		cloneFlags = 0
+	}
+
+	if len(containerNamespaces) > 0 || unshareFlags != 0 {
+		if !nsenter.Supported {
+			return fmt.Errorf("joining user, mount or time namespaces or creating time namespaces is not supported")
+		}
+
+		env = append(
+			env,
+			fmt.Sprintf("%s=%s", nsenter.JoinEnv, strings.Join(containerNamespaces, ",")),
+			fmt.Sprintf("%s=%d", nsenter.UnshareEnv, unshareFlags),
+			fmt.Sprintf("%s=%s", nsenter.TimeOffsetsEnv, formatTimeOffsets(container.Spec.Linux.TimeOffsets)),
+		)
+	}
+
+	// The container process is executed from a copy of the current executable
+	// (see `cloneBinary()`).
+	binary, err := cloneBinary()
 	if err != nil {
-		return fmt.Errorf("failed to retrieve executable: %w", err)
+		return err
 	}
+	defer binary.Close()
+
 	containerProcess := &exec.Cmd{
-		Path: self,
 		Args: append([]string{"yacr"}, containerArgs...),
 		SysProcAttr: &syscall.SysProcAttr{
 			Cloneflags: uintptr(cloneFlags),
@@ -126,6 +170,32 @@ func Create(rootDir string, opts CreateOpts) error {
 		Env: env,
 	}
 
+	// With a new user namespace, the container process waits until the uid/gid
+	// maps have been written before re-executing itself. This socket pair is
+	// used to tell the container process when it can do that.
+	var syncConn net.Conn
+	if newUserNamespace {
+		fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+		if err != nil {
+			return fmt.Errorf("failed to create socket pair: %w", err)
+		}
+		parentFile := os.NewFile(uintptr(fds[0]), "create-parent")
+		childFile := os.NewFile(uintptr(fds[1]), "create-child")
+		defer childFile.Close()
+
+		syncConn, err = net.FileConn(parentFile)
+		parentFile.Close()
+		if err != nil {
+			return fmt.Errorf("failed to create connection: %w", err)
+		}
+		defer syncConn.Close()
+
+		// The first extra file becomes `nsenter.SyncFd` in the child process.
+		containerProcess.ExtraFiles = []*os.File{childFile}
+	}
+
+	useBinary(containerProcess, binary)
+
 	logrus.WithFields(logrus.Fields{
 		"id":      container.ID(),
 		"process": containerProcess.String(),
@@ -142,28 +212,18 @@ func Create(rootDir string, opts CreateOpts) error {
 			"consoleSocket": opts.ConsoleSocket,
 		}).Debug("start container process with pty")
 
-		ptm, err := pty.Start(containerProcess)
-		if err != nil {
+		var ptm *os.File
+		if err := startInNamespaces(hostNamespaces, func() (err error) {
+			ptm, err = pty.StartWithSize(containerProcess, consoleSize(container.Spec.Process))
+			return err
+		}); err != nil {
 			return fmt.Errorf("failed to create container (1): %w", err)
 		}
 		defer ptm.Close()
 
-		// Connect to the socket in order to send the PTY file descriptor.
-		conn, err := net.Dial("unix", opts.ConsoleSocket)
-		if err != nil {
-			return fmt.Errorf("failed to dial console socket: %w", err)
-		}
-		defer conn.Close()
-
-		uc, ok := conn.(*net.UnixConn)
-		if !ok {
-			return errors.New("failed to cast unix socket")
+		if err := sendConsole(opts.ConsoleSocket, ptm); err != nil {
+			return err
 		}
-		defer uc.Close()
-
-		// Send file descriptor over socket.
-		oob := unix.UnixRights(int(ptm.Fd()))
-		uc.WriteMsgUnix([]byte(ptm.Name()), oob, nil)
 	} else {
 		logrus.WithFields(logrus.Fields{
 			"id": container.ID(),
@@ -173,57 +233,36 @@ func Create(rootDir string, opts CreateOpts) error {
 		containerProcess.Stdout = os.Stdout
 		containerProcess.Stderr = os.Stderr
 
-		if err := containerProcess.Start(); err != nil {
+		if err := startInNamespaces(hostNamespaces, containerProcess.Start); err != nil {
 			return fmt.Errorf("failed to create container (2): %w", err)
 		}
 	}
 
-	if cloneFlags&syscall.CLONE_NEWUSER == syscall.CLONE_NEWUSER {
-		newuidmap, err := exec.LookPath("newuidmap")
-		if err != nil {
+	if newUserNamespace {
+		if err := writeIDMappings(containerProcess.Process.Pid, container.Spec.Linux); err != nil {
+			// Let the container process know that it should not continue.
+			ipc.SendError(syncConn, ipc.NewError(ipc.StageNamespaces, err))
 			return err
 		}
 
-		var uidMap []string
-		for _, m := range container.Spec.Linux.UIDMappings {
-			uidMap = append(uidMap, []string{
-				strconv.Itoa(int(m.ContainerID)),
-				strconv.Itoa(int(m.HostID)),
-				strconv.Itoa(int(m.Size)),
-			}...)
-		}
-
-		newuidmapCmd := exec.Command(newuidmap, append(
-			[]string{strconv.Itoa(containerProcess.Process.Pid)}, uidMap...,
-		)...)
-		logrus.WithField("command", newuidmapCmd.String()).Debug("configuring uidmap")
-
-		if err := cmd.Run(newuidmapCmd); err != nil {
-			return fmt.Errorf("newuidmap failed: %w", err)
-		}
-
-		newgidmap, err := exec.LookPath("newgidmap")
-		if err != nil {
+		if err := ipc.SendMessage(syncConn, ipc.USERNS_MAPPED); err != nil {
 			return err
 		}
+		syncConn.Close()
+	}
 
-		var gidMap []string
-		for _, m := range container.Spec.Linux.GIDMappings {
-			gidMap = append(gidMap, []string{
-				strconv.Itoa(int(m.ContainerID)),
-				strconv.Itoa(int(m.HostID)),
-				strconv.Itoa(int(m.Size)),
-			}...)
-		}
+	// Move the container process into its own cgroup before it gets a chance to
+	// execute the user-specified program.
+	if err := setupCgroup(container, containerProcess.Process.Pid); err != nil {
+		return fmt.Errorf("failed to set up cgroup: %w", err)
+	}
 
-		newgidmapCmd := exec.Command(newgidmap, append(
-			[]string{strconv.Itoa(containerProcess.Process.Pid)}, gidMap...,
-		)...)
-		logrus.WithField("command", newgidmapCmd.String()).Debug("configuring gidmap")
+	if err := setRlimits(containerProcess.Process.Pid, container.Spec.Process.Rlimits); err != nil {
+		return err
+	}
 
-		if err := cmd.Run(newgidmapCmd); err != nil {
-			return fmt.Errorf("newgidmap failed: %w", err)
-		}
+	if err := setOOMScoreAdj(containerProcess.Process.Pid, container.Spec.Process.OOMScoreAdj); err != nil {
+		return err
 	}
 
 	// Wait until the container has "booted".
@@ -234,7 +273,7 @@ func Create(rootDir string, opts CreateOpts) error {
 	defer initConn.Close()
 
 	if err := ipc.AwaitMessage(initConn, ipc.CONTAINER_BOOTED); err != nil {
-		return err
+		return fmt.Errorf("container process failed to boot: %w", err)
 	}
 
 	logrus.WithFields(logrus.Fields{
@@ -259,7 +298,7 @@ func Create(rootDir string, opts CreateOpts) error {
 	// Wait until the container reached the "before pivot_root" step so that we
 	// can run `CreateRuntime` hooks.
 	if err := ipc.AwaitMessage(conn, ipc.CONTAINER_BEFORE_PIVOT); err != nil {
-		return fmt.Errorf("before_pivot: %w", err)
+		return fmt.Errorf("failed to create container: %w", err)
 	}
 
 	containerPid := containerProcess.Process.Pid
@@ -273,21 +312,26 @@ func Create(rootDir string, opts CreateOpts) error {
 	}
 
 	// Hooks to be run after the container has been created but before
-	// `pivot_root`.
+	// `pivot_root`. The (deprecated) `prestart` hooks are run first.
+	// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/config.md#prestart
 	// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/config.md#createruntime-hooks
-	if err := container.ExecuteHooks("CreateRuntime"); err != nil {
-		return err
+	for _, name := range []string{"prestart", "createRuntime"} {
+		if err := container.ExecuteHooks(name); err != nil {
+			// Let the container process know that it should not continue.
+			ipc.SendError(conn, ipc.NewError(ipc.StageHooks, err))
+			return err
+		}
 	}
 
 	// Notify the container that it can continue its initialization.
-	if err := ipc.SendMessage(conn, ipc.OK); err != nil {
+	if err := ipc.SendPayload(conn, ipc.OK, container.State); err != nil {
 		return err
 	}
 
 	// Wait until the container is ready (i.e. the container waits for the
 	// "start" command).
 	if err := ipc.AwaitMessage(conn, ipc.CONTAINER_WAIT_START); err != nil {
-		return err
+		return fmt.Errorf("failed to create container: %w", err)
 	}
 
 	// Update state.
@@ -301,3 +345,55 @@ func Create(rootDir string, opts CreateOpts) error {
 
 	return nil
 }
+
+// writeIDMappings writes the uid/gid maps of the user namespace of a process
+// with `newuidmap` and `newgidmap`.
+func writeIDMappings(pid int, linux *runtimespec.Linux) error {
+	newuidmap, err := exec.LookPath("newuidmap")
+	if err != nil {
+		return err
+	}
+
+	var uidMap []string
+	for _, m := range linux.UIDMappings {
+		uidMap = append(uidMap, []string{
+			strconv.Itoa(int(m.ContainerID)),
+			strconv.Itoa(int(m.HostID)),
+			strconv.Itoa(int(m.Size)),
+		}...)
+	}
+
+	newuidmapCmd := exec.Command(newuidmap, append(
+		[]string{strconv.Itoa(pid)}, uidMap...,
+	)...)
+	logrus.WithField("command", newuidmapCmd.String()).Debug("configuring uidmap")
+
+	if err := cmd.Run(newuidmapCmd); err != nil {
+		return fmt.Errorf("newuidmap failed: %w", err)
+	}
+
+	newgidmap, err := exec.LookPath("newgidmap")
+	if err != nil {
+		return err
+	}
+
+	var gidMap []string
+	for _, m := range linux.GIDMappings {
+		gidMap = append(gidMap, []string{
+			strconv.Itoa(int(m.ContainerID)),
+			strconv.Itoa(int(m.HostID)),
+			strconv.Itoa(int(m.Size)),
+		}...)
+	}
+
+	newgidmapCmd := exec.Command(newgidmap, append(
+		[]string{strconv.Itoa(pid)}, gidMap...,
+	)...)
+	logrus.WithField("command", newgidmapCmd.String()).Debug("configuring gidmap")
+
+	if err := cmd.Run(newgidmapCmd); err != nil {
+		return fmt.Errorf("newgidmap failed: %w", err)
+	}
+
+	return nil
+}
@@ -8,25 +8,43 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strconv"
+	"strings"
 	"syscall"
-	"time"
 
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/sirupsen/logrus"
 	"github.com/willdurand/containers/internal/yacr/container"
 	"github.com/willdurand/containers/internal/yacr/ipc"
+	"github.com/willdurand/containers/internal/yacr/nsenter"
+	"github.com/willdurand/containers/internal/yacr/seccomp"
 	"golang.org/x/sys/unix"
 )
 
 func CreateContainer(rootDir string, opts CreateOpts) error {
+	// Some of the process attributes (e.g., capabilities) are set per thread so
+	// we have to make sure that the thread that configures the process is the
+	// one that calls exec(3).
+	runtime.LockOSThread()
+
 	if os.Getenv("_YACR_CONTAINER_REEXEC") != "1" {
-		// Re-exec to take uid/gid map into account.
+		// Wait until the host has written the uid/gid maps and then re-exec to
+		// take them into account.
+		if err := awaitIDMappings(os.NewFile(uintptr(nsenter.SyncFd), "sync")); err != nil {
+			return err
+		}
+
 		logrus.Debug("re-executing create container")
-		// HACK: Pretty sure this is going to come back to bite us in the future
-		// but... it works for now.
-		time.Sleep(50 * time.Millisecond)
 
-		env := append(os.Environ(), "_YACR_CONTAINER_REEXEC=1")
+		// The namespaces must not be created again when re-executing.
+		var env []string
+		for _, e := range os.Environ() {
+			if !strings.HasPrefix(e, nsenter.UnshareEnv+"=") {
+				env = append(env, e)
+			}
+		}
+		env = append(env, "_YACR_CONTAINER_REEXEC=1")
 
 		if err := syscall.Exec("/proc/self/exe", os.Args, env); err != nil {
 			return err
@@ -81,27 +99,51 @@ func CreateContainer(rootDir string, opts CreateOpts) error {
 	}
 	defer conn.Close()
 
-	// TODO: send errors to the host.
+	// fail reports an error to the host (connected to `conn`) and returns it.
+	fail := func(stage string, err error) error {
+		ipcErr := ipc.NewError(stage, err)
+		if err := ipc.SendError(conn, ipcErr); err != nil {
+			logrus.WithError(err).Error("failed to report error to host")
+		}
+		return ipcErr
+	}
+
+	// The host moves this process into its cgroup before connecting to this
+	// container so we can now create the cgroup namespace, whose root is the
+	// current cgroup.
+	for _, ns := range container.Spec.Linux.Namespaces {
+		if ns.Type == runtimespec.CgroupNamespace && ns.Path == "" {
+			if err := unix.Unshare(unix.CLONE_NEWCGROUP); err != nil {
+				return fail(ipc.StageNamespaces, fmt.Errorf("failed to create cgroup namespace: %w", err))
+			}
+		}
+	}
 
 	rootfs := container.Rootfs()
 	if _, err := os.Stat(rootfs); errors.Is(err, fs.ErrNotExist) {
-		return fmt.Errorf("rootfs does not exist: %w", err)
+		return fail(ipc.StageRootfs, fmt.Errorf("rootfs does not exist: %w", err))
 	}
 
-	mountFlag := syscall.MS_PRIVATE
+	mountFlag := uintptr(syscall.MS_PRIVATE | syscall.MS_REC)
 	if opts.NoPivot {
-		mountFlag = syscall.MS_SLAVE
+		mountFlag = syscall.MS_SLAVE | syscall.MS_REC
+	}
+	// The propagation type of the root filesystem can be changed in the runtime
+	// configuration, except to "shared" (see `validateSpec()`).
+	if p := container.Spec.Linux.RootfsPropagation; p != "" {
+		mountFlag = propagationFlags[p]
 	}
 
 	// Prevent mount propagation back to other namespaces.
-	if err := syscall.Mount("none", "/", "", uintptr(mountFlag|syscall.MS_REC), ""); err != nil {
-		return fmt.Errorf("failed to prevent mount propagation: %w", err)
+	if err := syscall.Mount("none", "/", "", mountFlag, ""); err != nil {
+		return fail(ipc.StageRootfs, fmt.Errorf("failed to prevent mount propagation: %w", err))
 	}
 
-	if !opts.NoPivot {
-		// This seems to be needed for `pivot_root`.
+	if !opts.NoPivot || container.Spec.Root.Readonly {
+		// This seems to be needed for `pivot_root`. It is also needed to remount
+		// the root filesystem as read-only without changing the underlying mount.
 		if err := syscall.Mount(rootfs, rootfs, "bind", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
-			return fmt.Errorf("failed to bind-mount rootfs: %w", err)
+			return fail(ipc.StageRootfs, fmt.Errorf("failed to bind-mount rootfs: %w", err))
 		}
 	}
 
@@ -113,82 +155,53 @@ func CreateContainer(rootDir string, opts CreateOpts) error {
 		"mounts": mounts,
 	}).Debug("mount")
 
+	// A cgroup2 filesystem cannot be mounted in a user namespace when the
+	// container does not have its own cgroup namespace, in which case the mount
+	// is skipped.
+	skipCgroupMounts := hasNamespace(container.Spec.Linux.Namespaces, runtimespec.UserNamespace) &&
+		!hasNamespace(container.Spec.Linux.Namespaces, runtimespec.CgroupNamespace)
+
 	for _, m := range mounts {
-		// Create destination if it does not exist yet.
-		dest := filepath.Join(rootfs, m.Destination)
-		if _, err := os.Stat(dest); os.IsNotExist(err) {
-			if err := os.MkdirAll(dest, 0o755); err != nil {
-				return fmt.Errorf("failed to create directory: %w", err)
+		if err := mountFilesystem(rootfs, m); err != nil {
+			if skipCgroupMounts && errors.Is(err, errCgroupMountNotPermitted) {
+				logrus.WithFields(logrus.Fields{
+					"id":          container.ID(),
+					"destination": m.Destination,
+					"error":       err,
+				}).Warn("skipping cgroup mount")
+				continue
 			}
-		}
-
-		// TODO: add support for all `m.Options`
-		flags := syscall.MS_NOEXEC | syscall.MS_NOSUID | syscall.MS_NODEV
 
-		// HACK: this is mainly used to support default "rootless" specs (created
-		// with `runc spec --rootless`).
-		if len(m.Options) > 0 && m.Options[0] == "rbind" {
-			m.Type = "bind"
-			flags |= unix.MS_REC
-		}
-
-		if m.Type == "bind" {
-			flags |= syscall.MS_BIND
-		}
-
-		data := ""
-		switch m.Destination {
-		case "/dev", "/run":
-			flags = syscall.MS_NOSUID | syscall.MS_STRICTATIME
-			data = "mode=755,size=65536k"
-		case "/dev/pts":
-			flags &= ^syscall.MS_NODEV
-			data = "newinstance,ptmxmode=0666,mode=0620"
-		case "/dev/shm":
-			data = "mode=1777,size=65536k"
-		case "/sys", "/sys/fs/cgroup":
-			flags |= syscall.MS_RDONLY
-		}
-
-		if err := syscall.Mount(m.Source, dest, m.Type, uintptr(flags), data); err != nil {
 			logrus.WithFields(logrus.Fields{
 				"id":          container.ID(),
 				"source":      m.Source,
-				"destination": dest,
+				"destination": m.Destination,
 				"type":        m.Type,
 				"options":     m.Options,
 				"error":       err,
 			}).Error("failed to mount filesystem")
 
-			// TODO: handle `cgroup`
-			if !errors.Is(err, syscall.EPERM) {
-				return fmt.Errorf("failed to mount: %w", err)
+			ipcErr := ipc.NewError(ipc.StageMount, err)
+			ipcErr.Mount = &ipc.MountDetails{
+				Source:      m.Source,
+				Destination: m.Destination,
+				Type:        m.Type,
+				Options:     m.Options,
 			}
+			return fail(ipc.StageMount, ipcErr)
 		}
 	}
 
-	// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/config-linux.md#default-devices
-	for _, dev := range []string{
-		"/dev/null",
-		"/dev/zero",
-		"/dev/full",
-		"/dev/random",
-		"/dev/urandom",
-		"/dev/tty",
-	} {
-		dest := filepath.Join(rootfs, dev)
-
-		f, err := os.Create(dest)
-		if err != nil && !errors.Is(err, fs.ErrExist) {
-			return fmt.Errorf("failed to create device destination: %w", err)
-		}
-		if f != nil {
-			f.Close()
+	// Devices cannot be created with mknod(2) in a user namespace.
+	bindDevices := false
+	for _, ns := range container.Spec.Linux.Namespaces {
+		if ns.Type == runtimespec.UserNamespace {
+			bindDevices = true
 		}
+	}
 
-		if err := syscall.Mount(dev, dest, "bind", unix.MS_BIND, ""); err != nil {
-			return fmt.Errorf("failed to mount device: %w", err)
-		}
+	if err := createDevices(rootfs, containerDevices(container.Spec), bindDevices); err != nil {
+		return fail(ipc.StageDevices, err)
 	}
 
 	for _, link := range [][2]string{
@@ -201,23 +214,27 @@ func CreateContainer(rootDir string, opts CreateOpts) error {
 		dst := filepath.Join(rootfs, link[1])
 
 		if err := os.Symlink(src, dst); err != nil && !errors.Is(err, fs.ErrExist) {
-			return fmt.Errorf("failed to create symlink: %w", err)
+			return fail(ipc.StageDevices, fmt.Errorf("failed to create symlink: %w", err))
 		}
 	}
 
-	// if container.Spec.Process.Terminal {
-	// 	TODO: `/dev/console` is set up if terminal is enabled in the config by bind mounting the pseudoterminal pty to `/dev/console`.
-	// }
-
-	// TODO: create symlink for `/dev/ptmx`
+	if err := setupPtmx(rootfs); err != nil {
+		return fail(ipc.StageDevices, err)
+	}
 
-	// TODO: linux devices
+	if container.Spec.Process.Terminal {
+		if err := setupConsole(rootfs); err != nil {
+			return fail(ipc.StageDevices, err)
+		}
+	}
 
 	// Notify the host that we are about to execute `pivot_root`.
 	if err := ipc.SendMessage(conn, ipc.CONTAINER_BEFORE_PIVOT); err != nil {
 		return err
 	}
-	if err := ipc.AwaitMessage(conn, ipc.OK); err != nil {
+	// The host sends the state of the container, which is passed to the hooks
+	// executed in this process.
+	if err := ipc.AwaitPayload(conn, ipc.OK, &container.State); err != nil {
 		return fmt.Errorf("create: %w", err)
 	}
 
@@ -225,8 +242,8 @@ func CreateContainer(rootDir string, opts CreateOpts) error {
 	// pivot_root or any equivalent operation has been called. These hooks MUST
 	// be called after the `CreateRuntime` hooks.
 	// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/config.md#createcontainer-hooks
-	if err := container.ExecuteHooks("CreateContainer"); err != nil {
-		logrus.WithError(err).Error("CreateContainer hook failed")
+	if err := container.ExecuteHooks("createContainer"); err != nil {
+		return fail(ipc.StageHooks, err)
 	}
 
 	logrus.WithFields(logrus.Fields{
@@ -236,39 +253,73 @@ func CreateContainer(rootDir string, opts CreateOpts) error {
 	// Change root filesystem.
 	if opts.NoPivot {
 		if err := syscall.Chroot(rootfs); err != nil {
-			return fmt.Errorf("failed to change root filesystem: %w", err)
+			return fail(ipc.StagePivotRoot, fmt.Errorf("failed to change root filesystem: %w", err))
 		}
 	} else {
 		pivotDir := filepath.Join(rootfs, ".pivot_root")
 		if err := os.Mkdir(pivotDir, 0o777); err != nil {
-			return fmt.Errorf("failed to create '.pivot_root': %w", err)
+			return fail(ipc.StagePivotRoot, fmt.Errorf("failed to create '.pivot_root': %w", err))
 		}
 		if err := syscall.PivotRoot(rootfs, pivotDir); err != nil {
-			return fmt.Errorf("pivot_root failed: %w", err)
+			return fail(ipc.StagePivotRoot, fmt.Errorf("pivot_root failed: %w", err))
 		}
 		if err := syscall.Chdir("/"); err != nil {
-			return fmt.Errorf("chdir failed: %w", err)
+			return fail(ipc.StagePivotRoot, fmt.Errorf("chdir failed: %w", err))
 		}
 		pivotDir = filepath.Join("/", ".pivot_root")
 		if err := syscall.Unmount(pivotDir, syscall.MNT_DETACH); err != nil {
-			return fmt.Errorf("failed to unmount '.pivot_root': %w", err)
+			return fail(ipc.StagePivotRoot, fmt.Errorf("failed to unmount '.pivot_root': %w", err))
 		}
 		os.Remove(pivotDir)
 	}
 
+	// Kernel parameters are set before `/proc/sys` is (usually) made read-only
+	// below.
+	if err := setSysctl(container.Spec.Linux.Sysctl); err != nil {
+		return fail(ipc.StageSysctl, err)
+	}
+
+	// Paths are masked or made read-only after the other mounts so that they
+	// cannot be mounted over.
+	for _, path := range container.Spec.Linux.ReadonlyPaths {
+		if err := readonlyPath(path); err != nil {
+			return fail(ipc.StageRootfs, err)
+		}
+	}
+	for _, path := range container.Spec.Linux.MaskedPaths {
+		if err := maskPath(path); err != nil {
+			return fail(ipc.StageRootfs, err)
+		}
+	}
+
+	// The root filesystem is made read-only last because the mountpoints above
+	// might have to be created. The other mounts (e.g. `/dev/shm`) are not
+	// affected.
+	if container.Spec.Root.Readonly {
+		if err := remount("/", unix.MS_RDONLY); err != nil {
+			return fail(ipc.StageRootfs, err)
+		}
+	}
+
 	// Change current working directory.
 	if err := syscall.Chdir(container.Spec.Process.Cwd); err != nil {
-		return fmt.Errorf("failed to change directory: %w", err)
+		return fail(ipc.StageProcess, fmt.Errorf("failed to change directory: %w", err))
 	}
 
 	// Set up new hostname.
 	if err := syscall.Sethostname([]byte(container.Spec.Hostname)); err != nil {
-		return fmt.Errorf("failed to set hostname: %w", err)
+		return fail(ipc.StageProcess, fmt.Errorf("failed to set hostname: %w", err))
+	}
+
+	if domainname := container.Spec.Domainname; domainname != "" {
+		if err := unix.Setdomainname([]byte(domainname)); err != nil {
+			return fail(ipc.StageProcess, fmt.Errorf("failed to set domainname: %w", err))
+		}
 	}
 
 	// Avoid leaked file descriptors.
 	if err := closeExecFrom(3); err != nil {
-		return fmt.Errorf("failed to close exec fds: %w", err)
+		return fail(ipc.StageProcess, fmt.Errorf("failed to close exec fds: %w", err))
 	}
 
 	// At this point, the container has been created and when the host receives
@@ -290,15 +341,15 @@ func CreateContainer(rootDir string, opts CreateOpts) error {
 		"id": container.ID(),
 	}).Debug("waiting for start command")
 
-	if err := ipc.AwaitMessage(conn, ipc.START_CONTAINER); err != nil {
+	if err := ipc.AwaitPayload(conn, ipc.START_CONTAINER, &container.State); err != nil {
 		return err
 	}
 
 	// Hooks to be run after the start operation is called but before the
 	// container process is started.
 	// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/config.md#startcontainer-hooks
-	if err := container.ExecuteHooks("StartContainer"); err != nil {
-		logrus.WithError(err).Error("StartContainer hook failed")
+	if err := container.ExecuteHooks("startContainer"); err != nil {
+		return fail(ipc.StageHooks, err)
 	}
 
 	process := container.Spec.Process
@@ -310,10 +361,18 @@ func CreateContainer(rootDir string, opts CreateOpts) error {
 
 	argv0, err := exec.LookPath(process.Args[0])
 	if err != nil {
-		if err := ipc.SendMessage(conn, fmt.Sprintf("failed to retrieve executable: %s", err)); err != nil {
-			return err
-		}
-		return err
+		return fail(ipc.StageProcess, fmt.Errorf("failed to retrieve executable: %w", err))
+	}
+
+	// The seccomp filter is compiled before notifying the host so that errors
+	// can be reported.
+	seccompFilter, err := seccomp.Compile(container.Spec.Linux.Seccomp)
+	if err != nil {
+		return fail(ipc.StageProcess, fmt.Errorf("failed to compile seccomp filter: %w", err))
+	}
+
+	if err := setupProcess(process, seccompFilter); err != nil {
+		return fail(ipc.StageProcess, fmt.Errorf("failed to set up process: %w", err))
 	}
 
 	if err := ipc.SendMessage(conn, ipc.OK); err != nil {
@@ -330,6 +389,24 @@ func CreateContainer(rootDir string, opts CreateOpts) error {
 	return nil
 }
 
+// awaitIDMappings blocks until the host tells the current process that the
+// uid/gid maps of its user namespace have been written (see `Create()`). The
+// sync file is closed when this function returns.
+func awaitIDMappings(syncFile *os.File) error {
+	conn, err := net.FileConn(syncFile)
+	syncFile.Close()
+	if err != nil {
+		return fmt.Errorf("failed to create connection: %w", err)
+	}
+	defer conn.Close()
+
+	if err := ipc.AwaitMessage(conn, ipc.USERNS_MAPPED); err != nil {
+		return fmt.Errorf("failed to wait for uid/gid maps: %w", err)
+	}
+
+	return nil
+}
+
 func closeExecFrom(minFd int) error {
 	fdDir, err := os.Open("/proc/self/fd")
 	if err != nil {
@@ -0,0 +1,119 @@
+package yacr
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/willdurand/containers/internal/yacr/ipc"
+	"golang.org/x/sys/unix"
+)
+
+// socketPair returns the host and container sides of a sync socket.
+func socketPair(t *testing.T) (*os.File, *os.File) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	return os.NewFile(uintptr(fds[0]), "host"), os.NewFile(uintptr(fds[1]), "container")
+}
+
+func TestAwaitIDMappings(t *testing.T) {
+	host, container := socketPair(t)
+	defer host.Close()
+
+	if err := ipc.SendMessage(host, ipc.USERNS_MAPPED); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if err := awaitIDMappings(container); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+}
+
+func TestAwaitIDMappingsError(t *testing.T) {
+	host, container := socketPair(t)
+	defer host.Close()
+
+	ipc.SendError(host, ipc.NewError(ipc.StageNamespaces, os.ErrPermission))
+
+	err := awaitIDMappings(container)
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to wait for uid/gid maps") {
+		t.Errorf("expected an error, got: %v", err)
+	}
+}
+
+func TestAwaitIDMappingsClosed(t *testing.T) {
+	host, container := socketPair(t)
+	host.Close()
+
+	if err := awaitIDMappings(container); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+// fakeIDMapTools creates fake `newuidmap` and `newgidmap` programs, which
+// write their arguments to a file, and puts them in the `PATH`.
+func fakeIDMapTools(t *testing.T, exitCode string) string {
+	dir := t.TempDir()
+	for _, name := range []string{"newuidmap", "newgidmap"} {
+		script := "#!/bin/sh\necho \"$@\" > " + filepath.Join(dir, name+".args") + "\nexit " + exitCode + "\n"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+
+	return dir
+}
+
+func TestWriteIDMappings(t *testing.T) {
+	dir := fakeIDMapTools(t, "0")
+
+	err := writeIDMappings(123, &runtimespec.Linux{
+		UIDMappings: []runtimespec.LinuxIDMapping{
+			{ContainerID: 0, HostID: 1000, Size: 1},
+			{ContainerID: 1, HostID: 100000, Size: 65536},
+		},
+		GIDMappings: []runtimespec.LinuxIDMapping{
+			{ContainerID: 0, HostID: 1001, Size: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	for name, expected := range map[string]string{
+		"newuidmap": "123 0 1000 1 1 100000 65536\n",
+		"newgidmap": "123 0 1001 1\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, name+".args"))
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		if string(data) != expected {
+			t.Errorf("expected %s args: %q, got: %q", name, expected, data)
+		}
+	}
+}
+
+func TestWriteIDMappingsFailure(t *testing.T) {
+	fakeIDMapTools(t, "1")
+
+	err := writeIDMappings(123, &runtimespec.Linux{})
+	if err == nil || !strings.HasPrefix(err.Error(), "newuidmap failed") {
+		t.Errorf("expected an error, got: %v", err)
+	}
+}
+
+func TestWriteIDMappingsNotFound(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	if err := writeIDMappings(123, &runtimespec.Linux{}); err == nil {
+		t.Errorf("expected an error")
+	}
+}
@@ -50,15 +50,8 @@ func Delete(rootDir, containerId string, force bool) error {
 			}
 		}
 	} else {
-		for _, dev := range []string{
-			"/dev/null",
-			"/dev/zero",
-			"/dev/full",
-			"/dev/random",
-			"/dev/urandom",
-			"/dev/tty",
-		} {
-			mountpoint := filepath.Join(container.Spec.Root.Path, dev)
+		for _, dev := range containerDevices(container.Spec) {
+			mountpoint := filepath.Join(container.Spec.Root.Path, dev.Path)
 			if err := syscall.Unmount(mountpoint, 0); err != nil {
 				logrus.WithFields(logrus.Fields{
 					"id":         container.ID(),
@@ -80,12 +73,44 @@ func Delete(rootDir, containerId string, force bool) error {
 		}
 	}
 
-	if err := container.Destroy(); err != nil {
-		return err
+	// The cgroup is only destroyed when yacr has created it because it might be
+	// used by other processes otherwise. In this case, the container process is
+	// killed directly when the container is force deleted.
+	if force && !container.IsStopped() && !container.CgroupCreated() && container.State.Pid != 0 {
+		if err := syscall.Kill(container.State.Pid, syscall.SIGKILL); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"id":    container.ID(),
+				"error": err,
+			}).Debug("failed to kill container process")
+		}
 	}
 
+	cgroup, err := container.Cgroup()
+	if err == nil && container.CgroupCreated() {
+		err = cgroup.Destroy()
+	}
+	if err != nil {
+		if !force {
+			return err
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"id":    container.ID(),
+			"error": err,
+		}).Warn("failed to destroy cgroup")
+	}
+
+	// A failing `poststop` hook does not prevent the container from being
+	// deleted (see `ExecuteHooks()`).
 	// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/config.md#poststop
-	if err := container.ExecuteHooks("Poststop"); !force && err != nil {
+	if err := container.ExecutePoststopHooks(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"id":    container.ID(),
+			"error": err,
+		}).Warn("failed to execute poststop hooks")
+	}
+
+	if err := container.Destroy(); err != nil {
 		return err
 	}
 
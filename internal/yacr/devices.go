@@ -0,0 +1,261 @@
+package yacr
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+// defaultDevices is the list of devices that must be available in a container
+// in addition to the ones listed in the runtime configuration.
+//
+// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/config-linux.md#default-devices
+var defaultDevices = []runtimespec.LinuxDevice{
+	defaultDevice("/dev/null", 1, 3),
+	defaultDevice("/dev/zero", 1, 5),
+	defaultDevice("/dev/full", 1, 7),
+	defaultDevice("/dev/random", 1, 8),
+	defaultDevice("/dev/urandom", 1, 9),
+	defaultDevice("/dev/tty", 5, 0),
+}
+
+func defaultDevice(path string, major, minor int64) runtimespec.LinuxDevice {
+	mode := fs.FileMode(0o666)
+	uid := uint32(0)
+	gid := uint32(0)
+
+	return runtimespec.LinuxDevice{
+		Path:     path,
+		Type:     "c",
+		Major:    major,
+		Minor:    minor,
+		FileMode: &mode,
+		UID:      &uid,
+		GID:      &gid,
+	}
+}
+
+// containerDevices returns the default devices and the devices listed in the
+// runtime configuration. A device of the configuration replaces the default
+// device with the same path.
+func containerDevices(spec runtimespec.Spec) []runtimespec.LinuxDevice {
+	var devices []runtimespec.LinuxDevice
+	if spec.Linux != nil {
+		devices = spec.Linux.Devices
+	}
+
+	var all []runtimespec.LinuxDevice
+	for _, def := range defaultDevices {
+		overridden := false
+		for _, dev := range devices {
+			if filepath.Clean(dev.Path) == def.Path {
+				overridden = true
+				break
+			}
+		}
+
+		if !overridden {
+			all = append(all, def)
+		}
+	}
+
+	return append(all, devices...)
+}
+
+// validateDevices returns an error when a device is invalid.
+func validateDevices(devices []runtimespec.LinuxDevice) error {
+	for _, dev := range devices {
+		if !filepath.IsAbs(dev.Path) {
+			return fmt.Errorf("device path '%s' is not absolute", dev.Path)
+		}
+
+		if _, err := deviceType(dev.Type); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deviceType returns the file type of a device given its type in the runtime
+// configuration.
+func deviceType(t string) (uint32, error) {
+	switch t {
+	case "c", "u":
+		return unix.S_IFCHR, nil
+	case "b":
+		return unix.S_IFBLK, nil
+	case "p":
+		return unix.S_IFIFO, nil
+	default:
+		return 0, fmt.Errorf("invalid device type '%s'", t)
+	}
+}
+
+// createDevices creates the devices of a container under the given root
+// filesystem. In a user namespace, mknod(2) is not allowed so the devices are
+// bind-mounted from the host instead.
+func createDevices(rootfs string, devices []runtimespec.LinuxDevice, bind bool) error {
+	for _, dev := range devices {
+		// Clean the path first so that it cannot escape the root filesystem.
+		dest := filepath.Join(rootfs, filepath.Clean("/"+dev.Path))
+
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+
+		if !bind {
+			err := mknodDevice(dest, dev)
+			if err == nil {
+				continue
+			}
+
+			// This can happen when yacr runs in a container without `CAP_MKNOD`
+			// (for instance), in which case we try to bind-mount the device.
+			if !errors.Is(err, unix.EPERM) {
+				return err
+			}
+		}
+
+		if err := bindDevice(dest, dev.Path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mknodDevice creates a device with mknod(2) and sets its permissions and
+// owner.
+func mknodDevice(dest string, dev runtimespec.LinuxDevice) error {
+	fileType, err := deviceType(dev.Type)
+	if err != nil {
+		return err
+	}
+
+	mode := uint32(0o666)
+	if dev.FileMode != nil {
+		mode = uint32(dev.FileMode.Perm())
+	}
+
+	if err := os.Remove(dest); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove '%s': %w", dest, err)
+	}
+
+	if err := unix.Mknod(dest, fileType|mode, int(unix.Mkdev(uint32(dev.Major), uint32(dev.Minor)))); err != nil {
+		return fmt.Errorf("failed to create device '%s': %w", dev.Path, err)
+	}
+
+	// The mode passed to mknod(2) is modified by the umask.
+	if err := unix.Chmod(dest, mode); err != nil {
+		return fmt.Errorf("failed to chmod device '%s': %w", dev.Path, err)
+	}
+
+	uid, gid := 0, 0
+	if dev.UID != nil {
+		uid = int(*dev.UID)
+	}
+	if dev.GID != nil {
+		gid = int(*dev.GID)
+	}
+	if err := unix.Chown(dest, uid, gid); err != nil {
+		return fmt.Errorf("failed to chown device '%s': %w", dev.Path, err)
+	}
+
+	return nil
+}
+
+// bindDevice bind-mounts a device of the host.
+func bindDevice(dest, source string) error {
+	f, err := os.OpenFile(dest, os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create device destination: %w", err)
+	}
+	f.Close()
+
+	if err := unix.Mount(source, dest, "bind", unix.MS_BIND, ""); err != nil {
+		return fmt.Errorf("failed to mount device '%s': %w", source, err)
+	}
+
+	return nil
+}
+
+// setupPtmx makes `/dev/ptmx` a symlink to the `ptmx` device of the `devpts`
+// instance mounted on `/dev/pts`.
+//
+// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/config-linux.md#default-devices
+func setupPtmx(rootfs string) error {
+	dest := filepath.Join(rootfs, "/dev/ptmx")
+	if err := os.Remove(dest); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove '/dev/ptmx': %w", err)
+	}
+
+	if err := os.Symlink("pts/ptmx", dest); err != nil {
+		return fmt.Errorf("failed to create symlink: %w", err)
+	}
+
+	return nil
+}
+
+// setupConsole bind-mounts the pseudoterminal of the container process to
+// `/dev/console`. The container process is started with the slave side of the
+// PTY as its standard input.
+func setupConsole(rootfs string) error {
+	slave, err := os.Readlink("/proc/self/fd/0")
+	if err != nil {
+		return fmt.Errorf("failed to retrieve pty: %w", err)
+	}
+
+	return bindDevice(filepath.Join(rootfs, "/dev/console"), slave)
+}
+
+// deviceCgroupRules returns the device cgroup rules of a container. The
+// default devices, the devices listed in the runtime configuration and the
+// pseudoterminals are always allowed.
+func deviceCgroupRules(spec runtimespec.Spec) []runtimespec.LinuxDeviceCgroup {
+	if spec.Linux == nil || spec.Linux.Resources == nil || len(spec.Linux.Resources.Devices) == 0 {
+		return nil
+	}
+
+	wildcard := int64(-1)
+	rules := append([]runtimespec.LinuxDeviceCgroup{}, spec.Linux.Resources.Devices...)
+	rules = append(rules,
+		// mknod(2) is allowed, the devices cannot be used without an "allow" rule
+		// anyway.
+		runtimespec.LinuxDeviceCgroup{Allow: true, Type: "c", Major: &wildcard, Minor: &wildcard, Access: "m"},
+		runtimespec.LinuxDeviceCgroup{Allow: true, Type: "b", Major: &wildcard, Minor: &wildcard, Access: "m"},
+	)
+
+	for _, dev := range containerDevices(spec) {
+		if dev.Type == "p" {
+			continue
+		}
+
+		major, minor := dev.Major, dev.Minor
+		t := dev.Type
+		if t == "u" {
+			t = "c"
+		}
+
+		rules = append(rules, runtimespec.LinuxDeviceCgroup{Allow: true, Type: t, Major: &major, Minor: &minor, Access: "rwm"})
+	}
+
+	for _, dev := range [][2]int64{
+		// /dev/console
+		{5, 1},
+		// /dev/ptmx
+		{5, 2},
+		// /dev/pts/*
+		{136, -1},
+	} {
+		major, minor := dev[0], dev[1]
+		rules = append(rules, runtimespec.LinuxDeviceCgroup{Allow: true, Type: "c", Major: &major, Minor: &minor, Access: "rwm"})
+	}
+
+	return rules
+}
@@ -0,0 +1,55 @@
+package yacr
+
+import (
+	"testing"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestContainerDevices(t *testing.T) {
+	spec := runtimespec.Spec{
+		Linux: &runtimespec.Linux{
+			Devices: []runtimespec.LinuxDevice{
+				{Path: "/dev/null", Type: "c", Major: 1, Minor: 3},
+				{Path: "/dev/fuse", Type: "c", Major: 10, Minor: 229},
+			},
+		},
+	}
+
+	devices := containerDevices(spec)
+	if len(devices) != len(defaultDevices)+1 {
+		t.Fatalf("expected %d devices, got: %d", len(defaultDevices)+1, len(devices))
+	}
+
+	seen := make(map[string]int)
+	for _, dev := range devices {
+		seen[dev.Path]++
+	}
+	if seen["/dev/null"] != 1 || seen["/dev/fuse"] != 1 {
+		t.Errorf("unexpected devices: %+v", devices)
+	}
+}
+
+func TestDeviceCgroupRules(t *testing.T) {
+	if rules := deviceCgroupRules(runtimespec.Spec{Linux: &runtimespec.Linux{}}); rules != nil {
+		t.Errorf("expected no rules, got: %+v", rules)
+	}
+
+	spec := runtimespec.Spec{
+		Linux: &runtimespec.Linux{
+			Resources: &runtimespec.LinuxResources{
+				Devices: []runtimespec.LinuxDeviceCgroup{{Allow: false, Access: "rwm"}},
+			},
+		},
+	}
+
+	rules := deviceCgroupRules(spec)
+	if rules[0].Allow {
+		t.Errorf("expected the rules of the configuration first, got: %+v", rules[0])
+	}
+	for _, rule := range rules[1:] {
+		if !rule.Allow {
+			t.Errorf("expected an allow rule, got: %+v", rule)
+		}
+	}
+}
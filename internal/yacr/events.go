@@ -0,0 +1,99 @@
+package yacr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/willdurand/containers/internal/yacr/cgroups"
+	"github.com/willdurand/containers/internal/yacr/container"
+)
+
+type EventsOpts struct {
+	ID       string
+	Stats    bool
+	Interval time.Duration
+}
+
+// Event is the JSON representation of an event, which is compatible with the
+// one used by runc.
+type Event struct {
+	Type string      `json:"type"`
+	ID   string      `json:"id"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// Events writes the events of a container to `w`. When `opts.Stats` is true,
+// a single "stats" event is written. Otherwise, "stats" events are written
+// periodically as well as "oom" events when the OOM killer has been invoked,
+// until the container stops.
+func Events(rootDir string, opts EventsOpts, w io.Writer) error {
+	container, err := container.LoadWithBundleConfig(rootDir, opts.ID)
+	if err != nil {
+		return err
+	}
+
+	if !container.IsRunning() && !container.IsPaused() {
+		return fmt.Errorf("unexpected status '%s' for container '%s'", container.State.Status, container.ID())
+	}
+
+	cgroup, err := container.Cgroup()
+	if err != nil {
+		return err
+	}
+
+	if !cgroup.Exists() {
+		return fmt.Errorf("container '%s' does not have a cgroup", container.ID())
+	}
+
+	encoder := json.NewEncoder(w)
+	writeStats := func() error {
+		stats, err := cgroup.Stats()
+		if err != nil {
+			return fmt.Errorf("failed to retrieve stats: %w", err)
+		}
+
+		return encoder.Encode(Event{Type: "stats", ID: container.ID(), Data: stats})
+	}
+
+	if opts.Stats {
+		return writeStats()
+	}
+
+	if opts.Interval <= 0 {
+		return fmt.Errorf("invalid interval '%s'", opts.Interval)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	events, err := cgroup.Watch(done)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := writeStats(); err != nil {
+				return err
+			}
+
+		case event, ok := <-events:
+			// The channel is closed when the container has stopped.
+			if !ok {
+				return nil
+			}
+
+			if event == cgroups.EventOOM {
+				if err := encoder.Encode(Event{Type: "oom", ID: container.ID()}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
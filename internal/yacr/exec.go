@@ -0,0 +1,444 @@
+package yacr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/creack/pty"
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/sirupsen/logrus"
+	"github.com/willdurand/containers/internal/yacr/container"
+	"github.com/willdurand/containers/internal/yacr/ipc"
+	"github.com/willdurand/containers/internal/yacr/nsenter"
+	"github.com/willdurand/containers/internal/yacr/seccomp"
+	"golang.org/x/sys/unix"
+)
+
+type ExecOpts struct {
+	ID            string
+	ProcessPath   string
+	Args          []string
+	Env           []string
+	Cwd           string
+	Tty           bool
+	ConsoleSocket string
+	PidFile       string
+	Detach        bool
+}
+
+// execConfig is the configuration sent to the process created in the
+// container by `Exec()`.
+type execConfig struct {
+	Process *runtimespec.Process      `json:"process"`
+	Seccomp *runtimespec.LinuxSeccomp `json:"seccomp,omitempty"`
+}
+
+// Exec executes a new process in an existing container. It returns the exit
+// status of the process unless `opts.Detach` is `true`.
+//
+// When the process should have a terminal and no console socket is given, the
+// terminal is attached to the standard streams of the current process.
+//
+// The process is created in three steps: yacr re-executes itself with some
+// environment variables so that the `nsenter` package joins the namespaces of
+// the container process and forks. The child process is then configured by
+// `ExecProcess()` (in the container) and it eventually executes the program.
+func Exec(rootDir string, opts ExecOpts) (int, error) {
+	if !nsenter.Supported {
+		return -1, errors.New("yacr has been built without support for joining namespaces (cgo is required)")
+	}
+
+	container, err := container.LoadWithBundleConfig(rootDir, opts.ID)
+	if err != nil {
+		return -1, err
+	}
+
+	if !container.IsCreated() && !container.IsRunning() {
+		return -1, fmt.Errorf("unexpected status '%s' for container '%s'", container.State.Status, container.ID())
+	}
+
+	process, err := newExecProcess(container.Spec, opts)
+	if err != nil {
+		return -1, err
+	}
+
+	// Without a console socket, the PTY is attached to the standard streams of
+	// the current process, which is not possible when detaching.
+	if process.Terminal {
+		if opts.ConsoleSocket != "" {
+			if err := ipc.EnsureValidSockAddr(opts.ConsoleSocket, true); err != nil {
+				return -1, err
+			}
+		} else if opts.Detach {
+			return -1, errors.New("a console socket is required to detach a process with a terminal")
+		}
+	}
+
+	var namespaces []string
+	for _, f := range namespaceFiles {
+		for _, ns := range container.Spec.Linux.Namespaces {
+			if ns.Type == f.nsType {
+				namespaces = append(namespaces, f.name)
+			}
+		}
+	}
+
+	// This socket pair is used to communicate with the process created in the
+	// container (see the `ipc` package).
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create socket pair: %w", err)
+	}
+	parentFile := os.NewFile(uintptr(fds[0]), "exec-parent")
+	childFile := os.NewFile(uintptr(fds[1]), "exec-child")
+	defer childFile.Close()
+
+	conn, err := net.FileConn(parentFile)
+	parentFile.Close()
+	if err != nil {
+		return -1, fmt.Errorf("failed to create connection: %w", err)
+	}
+	defer conn.Close()
+
+	// Like the container process, this process is executed from a copy of the
+	// current executable (see `cloneBinary()`).
+	binary, err := cloneBinary()
+	if err != nil {
+		return -1, err
+	}
+	defer binary.Close()
+
+	// The first extra file becomes `nsenter.SyncFd` in the child process.
+	execProcess := &exec.Cmd{
+		Args: []string{"yacr", "exec", "process"},
+		Env: append(
+			os.Environ(),
+			fmt.Sprintf("%s=%d", nsenter.PidEnv, container.State.Pid),
+			fmt.Sprintf("%s=%s", nsenter.NamespacesEnv, strings.Join(namespaces, ",")),
+		),
+		ExtraFiles: []*os.File{childFile},
+	}
+	useBinary(execProcess, binary)
+
+	var ptm, pts *os.File
+	if process.Terminal {
+		ptm, pts, err = pty.Open()
+		if err != nil {
+			return -1, fmt.Errorf("failed to open pty: %w", err)
+		}
+		defer ptm.Close()
+		defer pts.Close()
+
+		if size := consoleSize(process); size != nil {
+			if err := pty.Setsize(ptm, size); err != nil {
+				return -1, fmt.Errorf("failed to set console size: %w", err)
+			}
+		}
+
+		// The process in the container becomes the session leader and
+		// acquires the PTY as its controlling terminal.
+		execProcess.Stdin = pts
+		execProcess.Stdout = pts
+		execProcess.Stderr = pts
+	} else {
+		execProcess.Stdin = os.Stdin
+		execProcess.Stdout = os.Stdout
+		execProcess.Stderr = os.Stderr
+	}
+
+	// The process in the container is orphaned when its parent exits. We want
+	// it to be re-parented to this process so that we can wait for it.
+	if !opts.Detach {
+		if err := unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0); err != nil {
+			return -1, fmt.Errorf("failed to set child subreaper: %w", err)
+		}
+	}
+
+	if err := execProcess.Start(); err != nil {
+		return -1, fmt.Errorf("failed to start exec process: %w", err)
+	}
+	childFile.Close()
+	// Reading from the PTY only fails once all the processes have closed the
+	// other side, which is how `attachConsole()` knows that the output has been
+	// copied.
+	if pts != nil {
+		pts.Close()
+	}
+
+	pid, err := awaitExecPid(conn)
+	// The parent process exits right after having sent the PID.
+	execProcess.Wait()
+	if err != nil {
+		return -1, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":  container.ID(),
+		"pid": pid,
+	}).Debug("exec process created")
+
+	if err := setupExecProcess(container, pid, process); err != nil {
+		unix.Kill(pid, unix.SIGKILL)
+		return -1, err
+	}
+
+	if err := ipc.SendPayload(conn, ipc.EXEC_CONFIG, execConfig{
+		Process: process,
+		Seccomp: container.Spec.Linux.Seccomp,
+	}); err != nil {
+		return -1, err
+	}
+
+	// The process should send a "OK" right before it calls exec(3) OR an error
+	// if something went wrong.
+	if err := ipc.AwaitMessage(conn, ipc.OK); err != nil {
+		return -1, fmt.Errorf("failed to execute process: %w", err)
+	}
+
+	if process.Terminal && opts.ConsoleSocket != "" {
+		if err := sendConsole(opts.ConsoleSocket, ptm); err != nil {
+			return -1, err
+		}
+	}
+
+	if opts.PidFile != "" {
+		if err := ioutil.WriteFile(opts.PidFile, []byte(strconv.Itoa(pid)), 0o644); err != nil {
+			return -1, fmt.Errorf("failed to write to pid file: %w", err)
+		}
+	}
+
+	if opts.Detach {
+		return 0, nil
+	}
+
+	detachConsole := func() {}
+	if process.Terminal && opts.ConsoleSocket == "" {
+		detachConsole, err = attachConsole(ptm)
+		if err != nil {
+			unix.Kill(pid, unix.SIGKILL)
+			return -1, err
+		}
+	}
+
+	exitStatus, err := waitProcess(pid)
+	detachConsole()
+
+	return exitStatus, err
+}
+
+// ExecProcess configures the current process (created by `Exec()` in the
+// namespaces of a container) and executes the user-specified program.
+func ExecProcess() error {
+	// See `CreateContainer()`.
+	runtime.LockOSThread()
+
+	syncFile := os.NewFile(uintptr(nsenter.SyncFd), "sync")
+	conn, err := net.FileConn(syncFile)
+	syncFile.Close()
+	if err != nil {
+		return fmt.Errorf("failed to create connection: %w", err)
+	}
+	defer conn.Close()
+
+	// fail reports an error to the host and returns it.
+	fail := func(err error) error {
+		ipcErr := ipc.NewError(ipc.StageProcess, err)
+		if err := ipc.SendError(conn, ipcErr); err != nil {
+			logrus.WithError(err).Error("failed to report error to host")
+		}
+		return ipcErr
+	}
+
+	var config execConfig
+	if err := ipc.AwaitPayload(conn, ipc.EXEC_CONFIG, &config); err != nil {
+		return fmt.Errorf("failed to read process configuration: %w", err)
+	}
+	process := config.Process
+
+	if err := prepareExecProcess(process); err != nil {
+		return fail(fmt.Errorf("failed to prepare process: %w", err))
+	}
+
+	argv0, err := exec.LookPath(process.Args[0])
+	if err != nil {
+		return fail(fmt.Errorf("failed to retrieve executable: %w", err))
+	}
+
+	seccompFilter, err := seccomp.Compile(config.Seccomp)
+	if err != nil {
+		return fail(fmt.Errorf("failed to compile seccomp filter: %w", err))
+	}
+
+	// Avoid leaked file descriptors.
+	if err := closeExecFrom(3); err != nil {
+		return fail(fmt.Errorf("failed to close exec fds: %w", err))
+	}
+
+	if err := setupProcess(process, seccompFilter); err != nil {
+		return fail(fmt.Errorf("failed to set up process: %w", err))
+	}
+
+	if err := ipc.SendMessage(conn, ipc.OK); err != nil {
+		return err
+	}
+	conn.Close()
+
+	if err := syscall.Exec(argv0, process.Args, process.Env); err != nil {
+		return fmt.Errorf("failed to exec %v: %w", process.Args, err)
+	}
+
+	return nil
+}
+
+// newExecProcess returns the configuration of the process to execute in a
+// container. When no process file is given, the configuration is derived from
+// the container process.
+func newExecProcess(spec runtimespec.Spec, opts ExecOpts) (*runtimespec.Process, error) {
+	var process runtimespec.Process
+
+	if opts.ProcessPath != "" {
+		data, err := ioutil.ReadFile(opts.ProcessPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read process file: %w", err)
+		}
+
+		if err := json.Unmarshal(data, &process); err != nil {
+			return nil, fmt.Errorf("failed to parse process file: %w", err)
+		}
+	} else {
+		process = *spec.Process
+		process.Args = opts.Args
+		process.Terminal = opts.Tty
+		process.Env = append(append([]string{}, spec.Process.Env...), opts.Env...)
+
+		if opts.Cwd != "" {
+			process.Cwd = opts.Cwd
+		}
+	}
+
+	if len(process.Args) == 0 {
+		return nil, errors.New("no command specified")
+	}
+
+	if process.Cwd == "" {
+		process.Cwd = "/"
+	}
+
+	if err := validateProcess(&process); err != nil {
+		return nil, err
+	}
+
+	return &process, nil
+}
+
+// awaitExecPid waits for the PID of the process created in the container.
+func awaitExecPid(conn net.Conn) (int, error) {
+	var payload ipc.PidPayload
+	if err := ipc.AwaitPayload(conn, ipc.EXEC_PID, &payload); err != nil {
+		return -1, fmt.Errorf("failed to join namespaces: %w", err)
+	}
+
+	return payload.Pid, nil
+}
+
+// setupExecProcess configures the process created in the container from the
+// host, like `Create()` does for the container process.
+func setupExecProcess(container *container.YacrContainer, pid int, process *runtimespec.Process) error {
+	cgroup, err := container.Cgroup()
+	if err != nil {
+		return err
+	}
+
+	if cgroup.Exists() {
+		if err := cgroup.AddProcess(pid); err != nil {
+			return fmt.Errorf("failed to join cgroup: %w", err)
+		}
+	}
+
+	if err := setRlimits(pid, process.Rlimits); err != nil {
+		return err
+	}
+
+	if err := setOOMScoreAdj(pid, process.OOMScoreAdj); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// prepareExecProcess sets the terminal, working directory and environment of
+// the current process before the program is looked up.
+func prepareExecProcess(process *runtimespec.Process) error {
+	if process.Terminal {
+		if _, err := unix.Setsid(); err != nil {
+			return fmt.Errorf("failed to create session: %w", err)
+		}
+
+		if err := unix.IoctlSetInt(0, unix.TIOCSCTTY, 0); err != nil {
+			return fmt.Errorf("failed to set controlling terminal: %w", err)
+		}
+	}
+
+	if err := os.Chdir(process.Cwd); err != nil {
+		return fmt.Errorf("failed to change directory: %w", err)
+	}
+
+	// Use the environment of the process so that `exec.LookPath()` uses the
+	// right `PATH`.
+	os.Clearenv()
+	for _, env := range process.Env {
+		if kv := strings.SplitN(env, "=", 2); len(kv) == 2 {
+			os.Setenv(kv[0], kv[1])
+		}
+	}
+
+	return nil
+}
+
+// waitProcess forwards the signals received by the current process to a
+// process executed in a container and waits for it. The process must be a child
+// of the current process (or one of its descendants when the current process
+// is a child subreaper).
+func waitProcess(pid int) (int, error) {
+	signals := make(chan os.Signal, 32)
+	signal.Notify(signals)
+	defer signal.Stop(signals)
+
+	go func() {
+		for sig := range signals {
+			// SIGWINCH is handled by `attachConsole()` when needed.
+			if sig == unix.SIGCHLD || sig == unix.SIGURG || sig == unix.SIGWINCH {
+				continue
+			}
+
+			unix.Kill(pid, sig.(syscall.Signal))
+		}
+	}()
+
+	var ws unix.WaitStatus
+	for {
+		_, err := unix.Wait4(pid, &ws, 0, nil)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, unix.EINTR) {
+			return -1, fmt.Errorf("failed to wait for process: %w", err)
+		}
+	}
+
+	if ws.Signaled() {
+		return 128 + int(ws.Signal()), nil
+	}
+
+	return ws.ExitStatus(), nil
+}
@@ -0,0 +1,96 @@
+package yacr
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestNewExecProcess(t *testing.T) {
+	spec := runtimespec.Spec{
+		Process: &runtimespec.Process{
+			Args: []string{"sleep", "100"},
+			Env:  []string{"PATH=/bin"},
+			Cwd:  "/root",
+		},
+	}
+
+	process, err := newExecProcess(spec, ExecOpts{
+		Args: []string{"ps"},
+		Env:  []string{"FOO=bar"},
+		Tty:  true,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if len(process.Args) != 1 || process.Args[0] != "ps" {
+		t.Errorf("expected args: [ps], got: %v", process.Args)
+	}
+	if len(process.Env) != 2 || process.Env[1] != "FOO=bar" {
+		t.Errorf("expected env: [PATH=/bin FOO=bar], got: %v", process.Env)
+	}
+	if process.Cwd != "/root" {
+		t.Errorf("expected cwd: /root, got: %s", process.Cwd)
+	}
+	if !process.Terminal {
+		t.Errorf("expected terminal: true, got: false")
+	}
+	if len(spec.Process.Env) != 1 {
+		t.Errorf("expected the container process to be unchanged, got: %v", spec.Process.Env)
+	}
+}
+
+func TestNewExecProcessFile(t *testing.T) {
+	processPath := filepath.Join(t.TempDir(), "process.json")
+	if err := os.WriteFile(processPath, []byte(`{"args":["id"],"cwd":""}`), 0o644); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	process, err := newExecProcess(runtimespec.Spec{}, ExecOpts{ProcessPath: processPath})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if len(process.Args) != 1 || process.Args[0] != "id" {
+		t.Errorf("expected args: [id], got: %v", process.Args)
+	}
+	if process.Cwd != "/" {
+		t.Errorf("expected cwd: /, got: %s", process.Cwd)
+	}
+}
+
+func TestNewExecProcessNoCommand(t *testing.T) {
+	spec := runtimespec.Spec{Process: &runtimespec.Process{Args: []string{"sh"}}}
+
+	if _, err := newExecProcess(spec, ExecOpts{}); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestWaitProcess(t *testing.T) {
+	for _, tc := range []struct {
+		script   string
+		expected int
+	}{
+		{script: "exit 0", expected: 0},
+		{script: "exit 3", expected: 3},
+		{script: "kill -TERM $$", expected: 143},
+	} {
+		cmd := exec.Command("/bin/sh", "-c", tc.script)
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		exitStatus, err := waitProcess(cmd.Process.Pid)
+		if err != nil {
+			t.Errorf("%s: expected no error, got: %v", tc.script, err)
+		}
+		if exitStatus != tc.expected {
+			t.Errorf("%s: expected: %d, got: %d", tc.script, tc.expected, exitStatus)
+		}
+	}
+}
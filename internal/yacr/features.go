@@ -0,0 +1,83 @@
+package yacr
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/opencontainers/runtime-spec/specs-go/features"
+	"github.com/willdurand/containers/internal/yacr/cgroups"
+	"github.com/willdurand/containers/internal/yacr/container"
+	"github.com/willdurand/containers/internal/yacr/seccomp"
+)
+
+// CgroupControllersAnnotation is the annotation of the features document that
+// lists the cgroup controllers used by yacr (comma-separated).
+const CgroupControllersAnnotation = "io.github.willdurand.containers.yacr.cgroup.controllers"
+
+// Features returns the features supported by yacr, derived from the code that
+// implements them.
+//
+// See: https://github.com/opencontainers/runtime-spec/blob/main/features.md
+func Features() *features.Features {
+	var mountOptions []string
+	for option := range mountFlags {
+		mountOptions = append(mountOptions, option)
+	}
+	for option := range propagationFlags {
+		mountOptions = append(mountOptions, option)
+	}
+	sort.Strings(mountOptions)
+
+	var namespaces []string
+	for _, ns := range namespaceFiles {
+		namespaces = append(namespaces, string(ns.nsType))
+	}
+	sort.Strings(namespaces)
+
+	var capabilities []string
+	for name := range capabilityNames {
+		capabilities = append(capabilities, name)
+	}
+	sort.Strings(capabilities)
+
+	enabled, disabled := true, false
+
+	return &features.Features{
+		OCIVersionMin: "1.0.0",
+		OCIVersionMax: runtimespec.Version,
+		Hooks:         container.SupportedHooks(),
+		MountOptions:  mountOptions,
+		Linux: &features.Linux{
+			Namespaces:   namespaces,
+			Capabilities: capabilities,
+			Cgroup: &features.Cgroup{
+				V1:          &disabled,
+				V2:          &enabled,
+				Systemd:     &disabled,
+				SystemdUser: &disabled,
+				Rdma:        &enabled,
+			},
+			Seccomp:  seccomp.Features(),
+			Apparmor: &features.Apparmor{Enabled: &disabled},
+			Selinux:  &features.Selinux{Enabled: &disabled},
+			IntelRdt: &features.IntelRdt{Enabled: &disabled},
+			MountExtensions: &features.MountExtensions{
+				IDMap: &features.IDMap{Enabled: &disabled},
+			},
+		},
+		Annotations: map[string]string{
+			CgroupControllersAnnotation: strings.Join(cgroups.Controllers(), ","),
+		},
+	}
+}
+
+// PrintFeatures writes the features document to the given writer.
+func PrintFeatures(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(Features())
+}
@@ -0,0 +1,75 @@
+package ipc
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// These are the stages of the creation of a container (or a process in a
+// container) that can fail.
+const (
+	StageNamespaces string = "namespaces"
+	StageRootfs     string = "rootfs"
+	StageMount      string = "mount"
+	StageDevices    string = "devices"
+	StagePivotRoot  string = "pivot_root"
+	StageSysctl     string = "sysctl"
+	StageHooks      string = "hooks"
+	StageProcess    string = "process"
+	StageNsenter    string = "nsenter"
+)
+
+// Error is an error that occurred in a container process and that has been
+// sent to the host.
+type Error struct {
+	Stage   string        `json:"stage"`
+	Message string        `json:"message"`
+	Errno   syscall.Errno `json:"errno,omitempty"`
+	Mount   *MountDetails `json:"mount,omitempty"`
+}
+
+// MountDetails describes the mount that caused an error.
+type MountDetails struct {
+	Source      string   `json:"source,omitempty"`
+	Destination string   `json:"destination"`
+	Type        string   `json:"type,omitempty"`
+	Options     []string `json:"options,omitempty"`
+}
+
+// NewError returns an `Error` for the given stage. The error number is
+// extracted from `err` when there is one. When `err` is already an `*Error`,
+// it is returned as is.
+func NewError(stage string, err error) *Error {
+	var ipcErr *Error
+	if errors.As(err, &ipcErr) {
+		return ipcErr
+	}
+
+	e := &Error{Stage: stage, Message: err.Error()}
+
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		e.Errno = errno
+	}
+
+	return e
+}
+
+func (e *Error) Error() string {
+	if e.Mount != nil {
+		return fmt.Sprintf("%s: %s (source: '%s', type: '%s')", e.Stage, e.Message, e.Mount.Source, e.Mount.Type)
+	}
+
+	return fmt.Sprintf("%s: %s", e.Stage, e.Message)
+}
+
+// Unwrap returns the error number (if any) so that `errors.Is()` can be used
+// with the errors received from a container process.
+func (e *Error) Unwrap() error {
+	if e.Errno == 0 {
+		return nil
+	}
+
+	return e.Errno
+}
@@ -1,9 +1,29 @@
 package ipc
 
+import "encoding/json"
+
 const (
 	CONTAINER_BOOTED       string = "container:booted"
 	CONTAINER_BEFORE_PIVOT string = "container:before-pivot"
 	CONTAINER_WAIT_START   string = "container:wait-start"
 	START_CONTAINER        string = "start-container"
+	USERNS_MAPPED          string = "userns-mapped"
+	EXEC_PID               string = "exec:pid"
+	EXEC_CONFIG            string = "exec:config"
 	OK                     string = "ok"
+	ERROR                  string = "error"
 )
+
+// Message is exchanged between the runtime (host) and the container
+// processes. Messages are encoded as JSON and each message is prefixed with
+// its length (4 bytes, big-endian).
+type Message struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// PidPayload is the payload of an `EXEC_PID` message.
+type PidPayload struct {
+	Pid int `json:"pid"`
+}
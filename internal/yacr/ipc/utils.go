@@ -1,10 +1,12 @@
 package ipc
 
 import (
+	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
-	"net"
 	"os"
 )
 
@@ -23,26 +25,115 @@ func EnsureValidSockAddr(sockAddr string, mustExist bool) error {
 	return nil
 }
 
-func AwaitMessage(conn net.Conn, expectedMessage string) error {
-	buf := make([]byte, len(expectedMessage))
+// maxMessageSize is the maximum size of an encoded message.
+const maxMessageSize = 1 << 20
 
-	n, err := conn.Read(buf)
+// WriteMessage encodes and writes a message. The message is written with a
+// single write(2) call.
+func WriteMessage(w io.Writer, msg Message) error {
+	data, err := json.Marshal(msg)
 	if err != nil {
-		return fmt.Errorf("failed to read from socket: %w", err)
+		return fmt.Errorf("failed to encode message '%s': %w", msg.Type, err)
 	}
 
-	msg := string(buf[0:n])
-	if msg != expectedMessage {
-		return fmt.Errorf("received unexpected message: %s", msg)
+	buf := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	copy(buf[4:], data)
+
+	if _, err := w.Write(buf); err != nil {
+		return fmt.Errorf("failed to send message '%s': %w", msg.Type, err)
 	}
 
 	return nil
 }
 
-func SendMessage(conn net.Conn, message string) error {
-	if _, err := conn.Write([]byte(message)); err != nil {
-		return fmt.Errorf("failed to send message '%s': %w", message, err)
+// ReadMessage reads and decodes a message. When the message is an error
+// message, the `*Error` it carries is returned.
+func ReadMessage(r io.Reader) (*Message, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("connection closed unexpectedly")
+		}
+		return nil, fmt.Errorf("failed to read from socket: %w", err)
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxMessageSize {
+		return nil, fmt.Errorf("message is too large (%d bytes)", size)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("failed to read from socket: %w", err)
+	}
+
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("failed to decode message: %w", err)
+	}
+
+	if msg.Type == ERROR {
+		if msg.Error == nil {
+			return nil, errors.New("received an error message without error")
+		}
+		return nil, msg.Error
+	}
+
+	return &msg, nil
+}
+
+// AwaitMessage reads a message and returns an error when it is not of the
+// expected type.
+func AwaitMessage(r io.Reader, expected string) error {
+	_, err := awaitMessage(r, expected)
+	return err
+}
+
+// AwaitPayload reads a message of the expected type and decodes its payload
+// into `v`.
+func AwaitPayload(r io.Reader, expected string, v interface{}) error {
+	msg, err := awaitMessage(r, expected)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(msg.Payload, v); err != nil {
+		return fmt.Errorf("failed to decode payload of message '%s': %w", expected, err)
 	}
 
 	return nil
 }
+
+func awaitMessage(r io.Reader, expected string) (*Message, error) {
+	msg, err := ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.Type != expected {
+		return nil, fmt.Errorf("received unexpected message: %s", msg.Type)
+	}
+
+	return msg, nil
+}
+
+// SendMessage sends a message without payload.
+func SendMessage(w io.Writer, msgType string) error {
+	return WriteMessage(w, Message{Type: msgType})
+}
+
+// SendPayload sends a message with a payload encoded as JSON.
+func SendPayload(w io.Writer, msgType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload of message '%s': %w", msgType, err)
+	}
+
+	return WriteMessage(w, Message{Type: msgType, Payload: data})
+}
+
+// SendError sends an error message.
+func SendError(w io.Writer, err *Error) error {
+	return WriteMessage(w, Message{Type: ERROR, Error: err})
+}
@@ -0,0 +1,90 @@
+package ipc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestSendAndAwaitMessage(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := SendMessage(&buf, OK); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := SendPayload(&buf, EXEC_PID, PidPayload{Pid: 123}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if err := AwaitMessage(&buf, OK); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+
+	var payload PidPayload
+	if err := AwaitPayload(&buf, EXEC_PID, &payload); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+	if payload.Pid != 123 {
+		t.Errorf("expected pid: 123, got: %d", payload.Pid)
+	}
+}
+
+func TestAwaitMessageUnexpected(t *testing.T) {
+	var buf bytes.Buffer
+	SendMessage(&buf, CONTAINER_BOOTED)
+
+	if err := AwaitMessage(&buf, OK); err == nil {
+		t.Error("expected an error")
+	}
+
+	if err := AwaitMessage(&buf, OK); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestSendError(t *testing.T) {
+	var buf bytes.Buffer
+
+	ipcErr := NewError(StageMount, fmt.Errorf("failed to mount '/data': %w", &os.PathError{
+		Op:   "mount",
+		Path: "/data",
+		Err:  syscall.ENOENT,
+	}))
+	ipcErr.Mount = &MountDetails{Source: "/src", Destination: "/data", Type: "bind"}
+
+	if err := SendError(&buf, ipcErr); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	err := AwaitMessage(&buf, OK)
+
+	var received *Error
+	if !errors.As(err, &received) {
+		t.Fatalf("expected an ipc error, got: %v", err)
+	}
+	if received.Stage != StageMount {
+		t.Errorf("expected stage: %s, got: %s", StageMount, received.Stage)
+	}
+	if received.Mount == nil || received.Mount.Destination != "/data" {
+		t.Errorf("expected mount details, got: %+v", received.Mount)
+	}
+	if !errors.Is(err, syscall.ENOENT) {
+		t.Errorf("expected ENOENT, got: %v", received.Errno)
+	}
+
+	expected := "mount: failed to mount '/data': mount /data: no such file or directory (source: '/src', type: 'bind')"
+	if err.Error() != expected {
+		t.Errorf("expected: %s, got: %s", expected, err.Error())
+	}
+}
+
+func TestNewErrorWithError(t *testing.T) {
+	ipcErr := &Error{Stage: StageSysctl, Message: "oops"}
+
+	if err := NewError(StageProcess, fmt.Errorf("wrapped: %w", ipcErr)); err != ipcErr {
+		t.Errorf("expected the same error, got: %v", err)
+	}
+}
@@ -24,7 +24,7 @@ func Kill(rootDir string, args []string) error {
 		return fmt.Errorf("%w", err)
 	}
 
-	if !container.IsCreated() && !container.IsRunning() {
+	if !container.IsCreated() && !container.IsRunning() && !container.IsPaused() {
 		return fmt.Errorf("unexpected status '%s' for container '%s'", container.State.Status, container.ID())
 	}
 
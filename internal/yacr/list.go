@@ -50,7 +50,7 @@ func List(rootDir string) (ContainerList, error) {
 
 		list = append(list, ContainerListItem{
 			ID:         container.ID(),
-			Status:     state.Status,
+			Status:     string(state.Status),
 			CreatedAt:  container.CreatedAt,
 			PID:        pid,
 			BundlePath: state.Bundle,
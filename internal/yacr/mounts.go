@@ -0,0 +1,242 @@
+package yacr
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+// maskPath makes a path inaccessible in the container. Files are masked with a
+// bind-mount of `/dev/null` and directories with a read-only tmpfs.
+//
+// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/config-linux.md#masked-paths
+func maskPath(path string) error {
+	fi, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to mask '%s': %w", path, err)
+	}
+
+	if fi.IsDir() {
+		err = unix.Mount("tmpfs", path, "tmpfs", unix.MS_RDONLY, "")
+	} else {
+		err = unix.Mount("/dev/null", path, "", unix.MS_BIND, "")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to mask '%s': %w", path, err)
+	}
+
+	return nil
+}
+
+// readonlyPath makes a path read-only in the container.
+//
+// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/config-linux.md#readonly-paths
+func readonlyPath(path string) error {
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err := unix.Mount(path, path, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("failed to bind-mount '%s': %w", path, err)
+	}
+
+	if err := remount(path, unix.MS_RDONLY); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// remount remounts a bind-mount with new flags. Some of the flags of the
+// existing mount are preserved because the kernel does not allow to clear them
+// (e.g., `nosuid`) in a user namespace.
+func remount(path string, flags uintptr) error {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return fmt.Errorf("failed to statfs '%s': %w", path, err)
+	}
+
+	// The `ST_*` flags returned by statfs(2) have the same values as the
+	// corresponding `MS_*` flags.
+	flags |= uintptr(st.Flags) & (unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC)
+	if flags&atimeFlags == 0 {
+		flags |= uintptr(st.Flags) & atimeFlags
+	}
+
+	if err := unix.Mount(path, path, "", unix.MS_BIND|unix.MS_REMOUNT|flags, ""); err != nil {
+		return fmt.Errorf("failed to remount '%s': %w", path, err)
+	}
+
+	return nil
+}
+
+// atimeFlags contains the flags that control how access times are updated.
+const atimeFlags = unix.MS_NOATIME | unix.MS_NODIRATIME | unix.MS_RELATIME | unix.MS_STRICTATIME
+
+// mountFlags maps the mount options to the flags they set (or clear).
+var mountFlags = map[string]struct {
+	clear bool
+	flag  uintptr
+}{
+	"async":         {true, unix.MS_SYNCHRONOUS},
+	"atime":         {true, unix.MS_NOATIME},
+	"bind":          {false, unix.MS_BIND},
+	"defaults":      {false, 0},
+	"dev":           {true, unix.MS_NODEV},
+	"diratime":      {true, unix.MS_NODIRATIME},
+	"dirsync":       {false, unix.MS_DIRSYNC},
+	"exec":          {true, unix.MS_NOEXEC},
+	"mand":          {false, unix.MS_MANDLOCK},
+	"noatime":       {false, unix.MS_NOATIME},
+	"nodev":         {false, unix.MS_NODEV},
+	"nodiratime":    {false, unix.MS_NODIRATIME},
+	"noexec":        {false, unix.MS_NOEXEC},
+	"nomand":        {true, unix.MS_MANDLOCK},
+	"norelatime":    {true, unix.MS_RELATIME},
+	"nostrictatime": {true, unix.MS_STRICTATIME},
+	"nosuid":        {false, unix.MS_NOSUID},
+	"rbind":         {false, unix.MS_BIND | unix.MS_REC},
+	"relatime":      {false, unix.MS_RELATIME},
+	"remount":       {false, unix.MS_REMOUNT},
+	"ro":            {false, unix.MS_RDONLY},
+	"rw":            {true, unix.MS_RDONLY},
+	"strictatime":   {false, unix.MS_STRICTATIME},
+	"suid":          {true, unix.MS_NOSUID},
+	"sync":          {false, unix.MS_SYNCHRONOUS},
+}
+
+// propagationFlags maps the mount options to the propagation types they set.
+var propagationFlags = map[string]uintptr{
+	"private":     unix.MS_PRIVATE,
+	"rprivate":    unix.MS_PRIVATE | unix.MS_REC,
+	"shared":      unix.MS_SHARED,
+	"rshared":     unix.MS_SHARED | unix.MS_REC,
+	"slave":       unix.MS_SLAVE,
+	"rslave":      unix.MS_SLAVE | unix.MS_REC,
+	"unbindable":  unix.MS_UNBINDABLE,
+	"runbindable": unix.MS_UNBINDABLE | unix.MS_REC,
+}
+
+// mountOptions represents the parsed options of a mount.
+type mountOptions struct {
+	flags       uintptr
+	propagation []uintptr
+	data        string
+}
+
+// parseMountOptions converts the options of a mount into mount(2) flags,
+// propagation types and filesystem-specific data. Options that are not known
+// are passed to the filesystem as data (e.g., `mode=755`).
+//
+// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/config.md#mounts
+func parseMountOptions(options []string) mountOptions {
+	var opts mountOptions
+	var data []string
+
+	for _, option := range options {
+		if f, ok := mountFlags[option]; ok {
+			if f.clear {
+				opts.flags &^= f.flag
+			} else {
+				opts.flags |= f.flag
+			}
+		} else if p, ok := propagationFlags[option]; ok {
+			opts.propagation = append(opts.propagation, p)
+		} else {
+			data = append(data, option)
+		}
+	}
+
+	opts.data = strings.Join(data, ",")
+
+	return opts
+}
+
+// errCgroupMountNotPermitted is returned by `mountFilesystem()` when a cgroup
+// filesystem cannot be mounted because of a lack of privileges.
+var errCgroupMountNotPermitted = errors.New("cgroup filesystem cannot be mounted")
+
+// mountFilesystem mounts a filesystem described in the runtime configuration
+// under the given root filesystem.
+func mountFilesystem(rootfs string, m runtimespec.Mount) error {
+	// The `cgroup` type refers to cgroup v1 but yacr only supports the unified
+	// hierarchy. When the container has its own cgroup namespace, the root of
+	// this filesystem is the cgroup of the container.
+	if m.Type == "cgroup" {
+		m.Type = "cgroup2"
+	}
+
+	opts := parseMountOptions(m.Options)
+	if m.Type == "bind" {
+		opts.flags |= unix.MS_BIND
+	}
+
+	dest := filepath.Join(rootfs, m.Destination)
+	if err := createMountpoint(dest, m.Source, opts.flags&unix.MS_BIND != 0); err != nil {
+		return err
+	}
+
+	// The flags other than `MS_BIND` and `MS_REC` are ignored by the kernel when
+	// a bind-mount is created, so we have to remount it to apply them.
+	flags := opts.flags
+	if flags&unix.MS_BIND != 0 {
+		flags &= unix.MS_BIND | unix.MS_REC
+	}
+
+	if err := unix.Mount(m.Source, dest, m.Type, flags, opts.data); err != nil {
+		if m.Type == "cgroup2" && errors.Is(err, unix.EPERM) {
+			return fmt.Errorf("failed to mount '%s': %w: %v", m.Destination, errCgroupMountNotPermitted, err)
+		}
+		return fmt.Errorf("failed to mount '%s': %w", m.Destination, err)
+	}
+
+	if flags != opts.flags {
+		if err := remount(dest, opts.flags&^(unix.MS_BIND|unix.MS_REC|unix.MS_REMOUNT)); err != nil {
+			return err
+		}
+	}
+
+	for _, propagation := range opts.propagation {
+		if err := unix.Mount("", dest, "", propagation, ""); err != nil {
+			return fmt.Errorf("failed to change propagation of '%s': %w", m.Destination, err)
+		}
+	}
+
+	return nil
+}
+
+// createMountpoint creates the destination of a mount when it does not exist
+// yet. When a file is bind-mounted, the mountpoint must be a file as well.
+func createMountpoint(dest, source string, bind bool) error {
+	if _, err := os.Stat(dest); !errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if bind {
+		if fi, err := os.Stat(source); err == nil && !fi.IsDir() {
+			if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+
+			f, err := os.OpenFile(dest, os.O_CREATE, 0o644)
+			if err != nil {
+				return fmt.Errorf("failed to create file: %w", err)
+			}
+			return f.Close()
+		}
+	}
+
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package yacr
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestParseMountOptions(t *testing.T) {
+	for _, tc := range []struct {
+		options     []string
+		flags       uintptr
+		propagation []uintptr
+		data        string
+	}{
+		{
+			options: nil,
+		},
+		{
+			options: []string{"nosuid", "strictatime", "mode=755", "size=65536k"},
+			flags:   unix.MS_NOSUID | unix.MS_STRICTATIME,
+			data:    "mode=755,size=65536k",
+		},
+		{
+			options: []string{"rbind", "nosuid", "noexec", "nodev", "ro"},
+			flags:   unix.MS_BIND | unix.MS_REC | unix.MS_NOSUID | unix.MS_NOEXEC | unix.MS_NODEV | unix.MS_RDONLY,
+		},
+		{
+			options: []string{"ro", "nosuid", "rw", "suid", "exec"},
+		},
+		{
+			options:     []string{"bind", "rprivate", "slave"},
+			flags:       unix.MS_BIND,
+			propagation: []uintptr{unix.MS_PRIVATE | unix.MS_REC, unix.MS_SLAVE},
+		},
+	} {
+		opts := parseMountOptions(tc.options)
+
+		if opts.flags != tc.flags {
+			t.Errorf("%v: expected flags: %#x, got: %#x", tc.options, tc.flags, opts.flags)
+		}
+
+		if len(opts.propagation) != len(tc.propagation) {
+			t.Errorf("%v: expected propagation: %v, got: %v", tc.options, tc.propagation, opts.propagation)
+		} else {
+			for i := range tc.propagation {
+				if opts.propagation[i] != tc.propagation[i] {
+					t.Errorf("%v: expected propagation: %v, got: %v", tc.options, tc.propagation, opts.propagation)
+				}
+			}
+		}
+
+		if opts.data != tc.data {
+			t.Errorf("%v: expected data: %q, got: %q", tc.options, tc.data, opts.data)
+		}
+	}
+}
+
+// inMountNamespace returns a temporary directory and `true` when the current
+// test is executed in its own mount namespace. Otherwise, it executes the test
+// again in a child process with a new mount namespace (when possible) and
+// returns `false`. The directory is removed by the parent process, once the
+// mounts are gone.
+func inMountNamespace(t *testing.T) (string, bool) {
+	if dir := os.Getenv("YACR_TEST_MOUNT_NAMESPACE"); dir != "" {
+		return dir, true
+	}
+
+	if os.Getuid() != 0 {
+		t.Skip("test requires root")
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^"+t.Name()+"$", "-test.v")
+	cmd.Env = append(os.Environ(), "YACR_TEST_MOUNT_NAMESPACE="+t.TempDir())
+	cmd.SysProcAttr = &syscall.SysProcAttr{Unshareflags: unix.CLONE_NEWNS}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("expected no error, got: %v (output: %s)", err, out)
+	}
+
+	return "", false
+}
+
+func TestMaskPath(t *testing.T) {
+	dir, ok := inMountNamespace(t)
+	if !ok {
+		return
+	}
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, []byte("secret"), 0o644); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	subdir := filepath.Join(dir, "subdir")
+	if err := os.MkdirAll(filepath.Join(subdir, "secret"), 0o755); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	for _, path := range []string{file, subdir, filepath.Join(dir, "does-not-exist")} {
+		if err := maskPath(path); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+
+	if data, err := os.ReadFile(file); err != nil || len(data) != 0 {
+		t.Errorf("expected masked file to be empty, got: %q (error: %v)", data, err)
+	}
+
+	if entries, err := os.ReadDir(subdir); err != nil || len(entries) != 0 {
+		t.Errorf("expected masked directory to be empty, got: %v (error: %v)", entries, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(subdir, "file"), nil, 0o644); !errors.Is(err, unix.EROFS) {
+		t.Errorf("expected masked directory to be read-only, got: %v", err)
+	}
+}
+
+func TestReadonlyPath(t *testing.T) {
+	dir, ok := inMountNamespace(t)
+	if !ok {
+		return
+	}
+
+	if err := unix.Mount("tmpfs", dir, "tmpfs", unix.MS_NOSUID, ""); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	subdir := filepath.Join(dir, "subdir")
+	if err := os.Mkdir(subdir, 0o755); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	for _, path := range []string{subdir, filepath.Join(dir, "does-not-exist")} {
+		if err := readonlyPath(path); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(subdir, "file"), nil, 0o644); !errors.Is(err, unix.EROFS) {
+		t.Errorf("expected directory to be read-only, got: %v", err)
+	}
+
+	var st unix.Statfs_t
+	if err := unix.Statfs(subdir, &st); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if st.Flags&unix.ST_NOSUID == 0 {
+		t.Errorf("expected nosuid to be preserved, got flags: %#x", st.Flags)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "file"), nil, 0o644); err != nil {
+		t.Errorf("expected parent directory to be writable, got: %v", err)
+	}
+}
+
+func TestRemount(t *testing.T) {
+	dir, ok := inMountNamespace(t)
+	if !ok {
+		return
+	}
+
+	// This is similar to the root filesystem of a container, which is a bind
+	// mount when it is made read-only.
+	if err := unix.Mount("tmpfs", dir, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOATIME, ""); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	rootfs := filepath.Join(dir, "rootfs")
+	if err := os.Mkdir(rootfs, 0o755); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := unix.Mount(rootfs, rootfs, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if err := remount(rootfs, unix.MS_RDONLY); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(rootfs, "file"), nil, 0o644); !errors.Is(err, unix.EROFS) {
+		t.Errorf("expected rootfs to be read-only, got: %v", err)
+	}
+
+	var st unix.Statfs_t
+	if err := unix.Statfs(rootfs, &st); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := int64(unix.ST_RDONLY | unix.ST_NOSUID | unix.ST_NODEV | unix.ST_NOATIME)
+	if st.Flags&expected != expected {
+		t.Errorf("expected flags %#x to be set, got: %#x", expected, st.Flags)
+	}
+}
+
+func TestRemountNotMounted(t *testing.T) {
+	if err := remount(filepath.Join(t.TempDir(), "does-not-exist"), unix.MS_RDONLY); err == nil {
+		t.Errorf("expected an error")
+	}
+}
@@ -0,0 +1,203 @@
+package yacr
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+// namespaceFiles maps the namespace types to the names of the files in
+// `/proc/<pid>/ns` and to the clone(2) flags. The order matters because the
+// user namespace must be joined first (to get the capabilities needed to join
+// the other namespaces) and the mount namespace last (because `/proc`
+// changes).
+var namespaceFiles = []struct {
+	nsType runtimespec.LinuxNamespaceType
+	name   string
+	flag   uintptr
+}{
+	{runtimespec.UserNamespace, "user", unix.CLONE_NEWUSER},
+	{runtimespec.IPCNamespace, "ipc", unix.CLONE_NEWIPC},
+	{runtimespec.UTSNamespace, "uts", unix.CLONE_NEWUTS},
+	{runtimespec.NetworkNamespace, "net", unix.CLONE_NEWNET},
+	{runtimespec.PIDNamespace, "pid", unix.CLONE_NEWPID},
+	{runtimespec.CgroupNamespace, "cgroup", unix.CLONE_NEWCGROUP},
+	{runtimespec.TimeNamespace, "time", unix.CLONE_NEWTIME},
+	{runtimespec.MountNamespace, "mnt", unix.CLONE_NEWNS},
+}
+
+// validateNamespaces checks the namespaces of a container.
+func validateNamespaces(namespaces []runtimespec.LinuxNamespace) error {
+	seen := make(map[runtimespec.LinuxNamespaceType]bool)
+	joinUser := false
+	newPid := false
+
+	for _, ns := range namespaces {
+		if _, err := namespaceFlag(ns.Type); err != nil {
+			return err
+		}
+
+		if seen[ns.Type] {
+			return fmt.Errorf("duplicate namespace: %s", ns.Type)
+		}
+		seen[ns.Type] = true
+
+		if ns.Path != "" && !filepath.IsAbs(ns.Path) {
+			return fmt.Errorf("namespace path '%s' must be absolute", ns.Path)
+		}
+
+		switch {
+		case ns.Type == runtimespec.UserNamespace && ns.Path != "":
+			joinUser = true
+		case ns.Type == runtimespec.PIDNamespace && ns.Path == "":
+			newPid = true
+		}
+	}
+
+	// When a user namespace is joined, the new namespaces are created after
+	// that in the container process, which does not work for a PID namespace.
+	if joinUser && newPid {
+		return errors.New("creating a PID namespace while joining a user namespace is not supported")
+	}
+
+	return nil
+}
+
+// hasNamespace returns `true` when the list of namespaces contains a namespace
+// of the given type.
+func hasNamespace(namespaces []runtimespec.LinuxNamespace, nsType runtimespec.LinuxNamespaceType) bool {
+	for _, ns := range namespaces {
+		if ns.Type == nsType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isRootless returns `true` when yacr creates a user namespace for a container,
+// which is how it runs in rootless mode.
+func isRootless(spec runtimespec.Spec) bool {
+	if spec.Linux == nil {
+		return false
+	}
+
+	for _, ns := range spec.Linux.Namespaces {
+		if ns.Type == runtimespec.UserNamespace && ns.Path == "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// namespaceFlag returns the clone(2) flag of a namespace type.
+func namespaceFlag(nsType runtimespec.LinuxNamespaceType) (uintptr, error) {
+	for _, f := range namespaceFiles {
+		if f.nsType == nsType {
+			return f.flag, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unsupported namespace: %s", nsType)
+}
+
+// startInNamespaces calls `start` from a dedicated OS thread that has joined
+// the given (existing) namespaces first, so that the processes cloned by
+// `start` are created in these namespaces. Only the namespaces that can be
+// joined by a multi-threaded process are supported, i.e. not the user and
+// mount namespaces.
+func startInNamespaces(namespaces []runtimespec.LinuxNamespace, start func() error) error {
+	if len(namespaces) == 0 {
+		return start()
+	}
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		// The thread is never unlocked so that the Go runtime terminates it when
+		// this goroutine returns, since its namespaces have been changed.
+		runtime.LockOSThread()
+
+		for _, ns := range namespaces {
+			if err := joinNamespace(ns); err != nil {
+				errCh <- err
+				return
+			}
+		}
+
+		errCh <- start()
+	}()
+
+	return <-errCh
+}
+
+// joinNamespace makes the current thread join an existing namespace.
+func joinNamespace(ns runtimespec.LinuxNamespace) error {
+	flag, err := namespaceFlag(ns.Type)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(ns.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open namespace: %w", err)
+	}
+	defer file.Close()
+
+	// The kernel verifies that the file refers to a namespace of this type.
+	if err := unix.Setns(int(file.Fd()), int(flag)); err != nil {
+		return fmt.Errorf("failed to join %s namespace '%s': %w", ns.Type, ns.Path, err)
+	}
+
+	return nil
+}
+
+// validateTimeOffsets checks the time offsets of a container, which require a
+// new time namespace.
+func validateTimeOffsets(offsets map[string]runtimespec.LinuxTimeOffset, namespaces []runtimespec.LinuxNamespace) error {
+	if len(offsets) == 0 {
+		return nil
+	}
+
+	newTime := false
+	for _, ns := range namespaces {
+		if ns.Type == runtimespec.TimeNamespace && ns.Path == "" {
+			newTime = true
+		}
+	}
+	if !newTime {
+		return errors.New("time offsets require a new time namespace")
+	}
+
+	for clock, offset := range offsets {
+		if clock != "monotonic" && clock != "boottime" {
+			return fmt.Errorf("invalid time offset clock '%s'", clock)
+		}
+
+		if offset.Nanosecs >= 1e9 {
+			return fmt.Errorf("invalid nanoseconds %d for clock '%s'", offset.Nanosecs, clock)
+		}
+	}
+
+	return nil
+}
+
+// formatTimeOffsets returns the time offsets in the format expected by
+// `/proc/<pid>/timens_offsets`.
+func formatTimeOffsets(offsets map[string]runtimespec.LinuxTimeOffset) string {
+	var lines []string
+	for clock, offset := range offsets {
+		lines = append(lines, fmt.Sprintf("%s %d %d", clock, offset.Secs, offset.Nanosecs))
+	}
+	sort.Strings(lines)
+
+	return strings.Join(lines, "\n")
+}
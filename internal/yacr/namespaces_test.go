@@ -0,0 +1,85 @@
+package yacr
+
+import (
+	"testing"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestValidateNamespaces(t *testing.T) {
+	err := validateNamespaces([]runtimespec.LinuxNamespace{
+		{Type: runtimespec.PIDNamespace},
+		{Type: runtimespec.MountNamespace},
+		{Type: runtimespec.NetworkNamespace, Path: "/var/run/netns/test"},
+		{Type: runtimespec.IPCNamespace, Path: "/proc/123/ns/ipc"},
+	})
+	if err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+}
+
+func TestValidateNamespacesInvalid(t *testing.T) {
+	for _, namespaces := range [][]runtimespec.LinuxNamespace{
+		{{Type: "unknown"}},
+		{{Type: runtimespec.NetworkNamespace}, {Type: runtimespec.NetworkNamespace, Path: "/proc/123/ns/net"}},
+		{{Type: runtimespec.NetworkNamespace, Path: "netns/test"}},
+		{{Type: runtimespec.UserNamespace, Path: "/proc/123/ns/user"}, {Type: runtimespec.PIDNamespace}},
+	} {
+		if err := validateNamespaces(namespaces); err == nil {
+			t.Errorf("expected an error for: %+v", namespaces)
+		}
+	}
+}
+
+func TestIsRootless(t *testing.T) {
+	for _, tc := range []struct {
+		namespaces []runtimespec.LinuxNamespace
+		expected   bool
+	}{
+		{namespaces: nil, expected: false},
+		{namespaces: []runtimespec.LinuxNamespace{{Type: runtimespec.PIDNamespace}}, expected: false},
+		{namespaces: []runtimespec.LinuxNamespace{{Type: runtimespec.UserNamespace}}, expected: true},
+		{namespaces: []runtimespec.LinuxNamespace{{Type: runtimespec.UserNamespace, Path: "/proc/123/ns/user"}}, expected: false},
+	} {
+		spec := runtimespec.Spec{Linux: &runtimespec.Linux{Namespaces: tc.namespaces}}
+		if got := isRootless(spec); got != tc.expected {
+			t.Errorf("%+v: expected: %t, got: %t", tc.namespaces, tc.expected, got)
+		}
+	}
+
+	if isRootless(runtimespec.Spec{}) {
+		t.Errorf("expected: false, got: true")
+	}
+}
+
+func TestValidateTimeOffsets(t *testing.T) {
+	offsets := map[string]runtimespec.LinuxTimeOffset{
+		"monotonic": {Secs: 3600},
+		"boottime":  {Secs: -60, Nanosecs: 500},
+	}
+
+	err := validateTimeOffsets(offsets, []runtimespec.LinuxNamespace{{Type: runtimespec.TimeNamespace}})
+	if err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+
+	if err := validateTimeOffsets(nil, nil); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+}
+
+func TestValidateTimeOffsetsInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		offsets    map[string]runtimespec.LinuxTimeOffset
+		namespaces []runtimespec.LinuxNamespace
+	}{
+		{map[string]runtimespec.LinuxTimeOffset{"monotonic": {Secs: 1}}, nil},
+		{map[string]runtimespec.LinuxTimeOffset{"monotonic": {Secs: 1}}, []runtimespec.LinuxNamespace{{Type: runtimespec.TimeNamespace, Path: "/proc/123/ns/time"}}},
+		{map[string]runtimespec.LinuxTimeOffset{"realtime": {Secs: 1}}, []runtimespec.LinuxNamespace{{Type: runtimespec.TimeNamespace}}},
+		{map[string]runtimespec.LinuxTimeOffset{"boottime": {Nanosecs: 1e9}}, []runtimespec.LinuxNamespace{{Type: runtimespec.TimeNamespace}}},
+	} {
+		if err := validateTimeOffsets(tc.offsets, tc.namespaces); err == nil {
+			t.Errorf("expected an error for: %+v", tc)
+		}
+	}
+}
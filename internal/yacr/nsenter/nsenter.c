@@ -0,0 +1,252 @@
+//go:build cgo
+
+// nsenter() is called by a constructor (see nsenter_cgo.go) so that it runs
+// before the Go runtime starts. This is needed because the kernel does not
+// allow multi-threaded processes to join user and mount namespaces.
+
+#define _GNU_SOURCE
+#include <arpa/inet.h>
+#include <errno.h>
+#include <fcntl.h>
+#include <sched.h>
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/stat.h>
+#include <sys/types.h>
+#include <unistd.h>
+
+#define PID_ENV "_YACR_NSENTER_PID"
+#define NAMESPACES_ENV "_YACR_NSENTER_NAMESPACES"
+#define JOIN_ENV "_YACR_NSENTER_JOIN"
+#define UNSHARE_ENV "_YACR_NSENTER_UNSHARE"
+#define TIME_OFFSETS_ENV "_YACR_NSENTER_TIME_OFFSETS"
+#define SYNC_FD 3
+#define MAX_NAMESPACES 8
+
+// sync_fd is the file descriptor used to report to the parent process, if
+// any. Only `yacr exec` expects messages from nsenter on `SYNC_FD`.
+static int sync_fd = -1;
+
+// send_message writes a JSON-encoded message prefixed with its length, as
+// expected by the `ipc` package.
+static int send_message(const char *json)
+{
+  size_t len = strlen(json);
+  char buf[4 + 1024];
+
+  if (len > sizeof(buf) - 4) {
+    errno = EMSGSIZE;
+    return -1;
+  }
+
+  uint32_t size = htonl(len);
+  memcpy(buf, &size, 4);
+  memcpy(buf + 4, json, len);
+
+  return write(sync_fd, buf, 4 + len) < 0 ? -1 : 0;
+}
+
+// json_escape copies `src` to `dst` as the content of a JSON string.
+static void json_escape(char *dst, size_t size, const char *src)
+{
+  size_t n = 0;
+
+  for (; *src != '\0' && n + 7 < size; src++) {
+    unsigned char c = *src;
+
+    if (c == '"' || c == '\\') {
+      dst[n++] = '\\';
+      dst[n++] = c;
+    } else if (c < 0x20) {
+      n += snprintf(dst + n, size - n, "\\u%04x", c);
+    } else {
+      dst[n++] = c;
+    }
+  }
+
+  dst[n] = '\0';
+}
+
+// fail sends an error message to the parent process (or prints it when there
+// is no parent process to report to) and exits.
+static void fail(const char *action, const char *arg)
+{
+  int err = errno;
+  char msg[512], escaped[768], json[1024];
+
+  snprintf(msg, sizeof(msg), "failed to %s '%s': %s", action, arg, strerror(err));
+  json_escape(escaped, sizeof(escaped), msg);
+  snprintf(json, sizeof(json),
+           "{\"type\":\"error\",\"error\":{\"stage\":\"nsenter\",\"message\":\"%s\",\"errno\":%d}}",
+           escaped, err);
+
+  if (send_message(json) < 0) {
+    fprintf(stderr, "nsenter: %s\n", msg);
+  }
+
+  _exit(1);
+}
+
+// same_namespace returns 1 when the two namespace files refer to the same
+// namespace, 0 otherwise.
+static int same_namespace(const char *a, const char *b)
+{
+  struct stat st_a, st_b;
+
+  if (stat(a, &st_a) < 0 || stat(b, &st_b) < 0) {
+    return 0;
+  }
+
+  return st_a.st_dev == st_b.st_dev && st_a.st_ino == st_b.st_ino;
+}
+
+// set_time_offsets writes the offsets of the time namespace that has been
+// created for the children of the current process. The offsets cannot be
+// changed once a process has entered this namespace.
+static void set_time_offsets(const char *offsets)
+{
+  if (offsets == NULL || *offsets == '\0') {
+    return;
+  }
+
+  int fd = open("/proc/self/timens_offsets", O_WRONLY | O_CLOEXEC);
+  if (fd < 0) {
+    fail("open", "/proc/self/timens_offsets");
+  }
+
+  if (write(fd, offsets, strlen(offsets)) < 0) {
+    fail("set time offsets", offsets);
+  }
+
+  close(fd);
+}
+
+// nsjoin joins the namespaces given as a comma-separated list of
+// "<name>:<path>" pairs and then unshares the namespaces given as clone(2)
+// flags (if any).
+static void nsjoin(const char *namespaces, const char *flags)
+{
+  char *list = strdup(namespaces != NULL ? namespaces : "");
+  char *names[MAX_NAMESPACES];
+  int fds[MAX_NAMESPACES];
+  int n = 0;
+
+  for (char *ns = strtok(list, ","); ns != NULL && n < MAX_NAMESPACES; ns = strtok(NULL, ",")) {
+    char *path = strchr(ns, ':');
+    if (path == NULL) {
+      errno = EINVAL;
+      fail("parse namespace", ns);
+    }
+    *path++ = '\0';
+
+    char self[64];
+    snprintf(self, sizeof(self), "/proc/self/ns/%s", ns);
+
+    // This happens when the process re-executes itself.
+    if (same_namespace(path, self)) {
+      continue;
+    }
+
+    fds[n] = open(path, O_RDONLY | O_CLOEXEC);
+    if (fds[n] < 0) {
+      fail("open", path);
+    }
+
+    names[n] = ns;
+    n++;
+  }
+
+  for (int i = 0; i < n; i++) {
+    if (setns(fds[i], 0) < 0) {
+      fail("join namespace", names[i]);
+    }
+
+    close(fds[i]);
+  }
+
+  int unshare_flags = flags != NULL ? atoi(flags) : 0;
+  if (unshare_flags != 0 && unshare(unshare_flags) < 0) {
+    fail("unshare namespaces", flags);
+  }
+
+  // The current process enters the new time namespace when it calls execve(2).
+  if (unshare_flags & CLONE_NEWTIME) {
+    set_time_offsets(getenv(TIME_OFFSETS_ENV));
+  }
+
+  free(list);
+}
+
+void nsenter(void)
+{
+  const char *join = getenv(JOIN_ENV);
+  const char *flags = getenv(UNSHARE_ENV);
+  if (join != NULL || flags != NULL) {
+    nsjoin(join, flags);
+    return;
+  }
+
+  const char *pid = getenv(PID_ENV);
+  const char *namespaces = getenv(NAMESPACES_ENV);
+
+  if (pid == NULL || namespaces == NULL) {
+    return;
+  }
+
+  sync_fd = SYNC_FD;
+
+  char *list = strdup(namespaces);
+  char *names[MAX_NAMESPACES];
+  int fds[MAX_NAMESPACES];
+  int n = 0;
+
+  // All the namespace files must be opened before joining any namespace
+  // because `/proc` might not be accessible after.
+  for (char *ns = strtok(list, ","); ns != NULL && n < MAX_NAMESPACES; ns = strtok(NULL, ",")) {
+    char path[64], self[64];
+    snprintf(path, sizeof(path), "/proc/%s/ns/%s", pid, ns);
+    snprintf(self, sizeof(self), "/proc/self/ns/%s", ns);
+
+    // Joining the current user namespace is not allowed.
+    if (same_namespace(path, self)) {
+      continue;
+    }
+
+    fds[n] = open(path, O_RDONLY | O_CLOEXEC);
+    if (fds[n] < 0) {
+      fail("open", path);
+    }
+
+    names[n] = ns;
+    n++;
+  }
+
+  for (int i = 0; i < n; i++) {
+    if (setns(fds[i], 0) < 0) {
+      fail("join namespace", names[i]);
+    }
+
+    close(fds[i]);
+  }
+
+  // The PID namespace only applies to the children of the current process.
+  pid_t child = fork();
+  if (child < 0) {
+    fail("fork", pid);
+  }
+
+  if (child > 0) {
+    char json[64];
+    snprintf(json, sizeof(json), "{\"type\":\"exec:pid\",\"payload\":{\"pid\":%d}}", child);
+
+    if (send_message(json) < 0) {
+      _exit(1);
+    }
+
+    _exit(0);
+  }
+
+  free(list);
+}
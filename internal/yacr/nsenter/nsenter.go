@@ -0,0 +1,28 @@
+// Package nsenter allows yacr to join the namespaces of a container before the
+// Go runtime starts.
+//
+// When the `_YACR_NSENTER_PID` and `_YACR_NSENTER_NAMESPACES` environment
+// variables are set, the current process joins the namespaces (a
+// comma-separated list of names as found in `/proc/<pid>/ns`) of the given
+// process and forks. The parent process sends the PID of the child to file
+// descriptor 3 (as an `exec:pid` message, see the `ipc` package) and exits.
+// The child process continues with the Go runtime. On error, an error message
+// is sent instead.
+//
+// When the `_YACR_NSENTER_JOIN` or `_YACR_NSENTER_UNSHARE` environment
+// variables are set, the current process joins the namespaces given as a
+// comma-separated list of "<name>:<path>" pairs, in order, without forking. It
+// then creates the namespaces given as clone(2) flags. When a time namespace
+// is created, the offsets in `_YACR_NSENTER_TIME_OFFSETS` (in the format of
+// `/proc/<pid>/timens_offsets`) are applied. On error, a message is written to
+// standard error and the process exits.
+package nsenter
+
+const (
+	PidEnv         string = "_YACR_NSENTER_PID"
+	NamespacesEnv  string = "_YACR_NSENTER_NAMESPACES"
+	JoinEnv        string = "_YACR_NSENTER_JOIN"
+	UnshareEnv     string = "_YACR_NSENTER_UNSHARE"
+	TimeOffsetsEnv string = "_YACR_NSENTER_TIME_OFFSETS"
+	SyncFd         int    = 3
+)
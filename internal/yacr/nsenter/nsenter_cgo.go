@@ -0,0 +1,14 @@
+package nsenter
+
+/*
+#cgo CFLAGS: -Wall
+extern void nsenter(void);
+void __attribute__((constructor)) init(void) {
+	nsenter();
+}
+*/
+import "C"
+
+// Supported indicates whether yacr has been built with support for joining
+// namespaces.
+const Supported = true
@@ -0,0 +1,7 @@
+//go:build !cgo
+
+package nsenter
+
+// Supported indicates whether yacr has been built with support for joining
+// namespaces.
+const Supported = false
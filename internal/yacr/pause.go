@@ -0,0 +1,93 @@
+package yacr
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/willdurand/containers/internal/constants"
+	"github.com/willdurand/containers/internal/yacr/container"
+)
+
+// Pause suspends all the processes of a running container by freezing its
+// cgroup.
+func Pause(rootDir, containerId string) error {
+	container, err := container.LoadWithBundleConfig(rootDir, containerId)
+	if err != nil {
+		return err
+	}
+
+	if !container.IsRunning() {
+		return fmt.Errorf("unexpected status '%s' for container '%s'", container.State.Status, container.ID())
+	}
+
+	cgroup, err := container.Cgroup()
+	if err != nil {
+		return err
+	}
+
+	if !cgroup.Exists() {
+		return fmt.Errorf("container '%s' does not have a cgroup", container.ID())
+	}
+
+	if err := cgroup.Freeze(); err != nil {
+		return fmt.Errorf("failed to freeze container '%s': %w", container.ID(), err)
+	}
+
+	if err := container.UpdateStatus(constants.StateRunning, constants.StatePaused); err != nil {
+		// The cgroup must match the status of the container, otherwise it could
+		// not be resumed.
+		if err := cgroup.Thaw(); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"id":    container.ID(),
+				"error": err,
+			}).Warn("failed to thaw container")
+		}
+
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id": container.ID(),
+	}).Info("pause: ok")
+
+	return nil
+}
+
+// Resume resumes all the processes of a paused container.
+func Resume(rootDir, containerId string) error {
+	container, err := container.LoadWithBundleConfig(rootDir, containerId)
+	if err != nil {
+		return err
+	}
+
+	if !container.IsPaused() {
+		return fmt.Errorf("unexpected status '%s' for container '%s'", container.State.Status, container.ID())
+	}
+
+	cgroup, err := container.Cgroup()
+	if err != nil {
+		return err
+	}
+
+	if err := cgroup.Thaw(); err != nil {
+		return fmt.Errorf("failed to thaw container '%s': %w", container.ID(), err)
+	}
+
+	if err := container.UpdateStatus(constants.StatePaused, constants.StateRunning); err != nil {
+		// Like in `Pause()`, the cgroup must match the status of the container.
+		if err := cgroup.Freeze(); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"id":    container.ID(),
+				"error": err,
+			}).Warn("failed to freeze container")
+		}
+
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id": container.ID(),
+	}).Info("resume: ok")
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package yacr
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/willdurand/containers/internal/constants"
+	"github.com/willdurand/containers/internal/yacr/container"
+)
+
+// newTestContainerWithStatus saves a container with the given status and
+// returns the root directory.
+func newTestContainerWithStatus(t *testing.T, status runtimespec.ContainerState) string {
+	bundle := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bundle, "config.json"), []byte(`{"ociVersion":"1.2.1","root":{"path":"rootfs"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rootDir := t.TempDir()
+	c, err := container.New(rootDir, "yacr-test-pause", bundle)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	// The current process is used as container process so that the container
+	// is not considered as stopped.
+	c.SetPid(os.Getpid())
+	c.State.Status = status
+	if err := c.Save(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	return rootDir
+}
+
+func TestPauseNotRunning(t *testing.T) {
+	for _, status := range []runtimespec.ContainerState{constants.StateCreated, constants.StatePaused} {
+		rootDir := newTestContainerWithStatus(t, status)
+
+		err := Pause(rootDir, "yacr-test-pause")
+		if err == nil || !strings.Contains(err.Error(), "unexpected status '"+string(status)+"'") {
+			t.Errorf("expected an unexpected status error, got: %v", err)
+		}
+	}
+}
+
+func TestResumeNotPaused(t *testing.T) {
+	for _, status := range []runtimespec.ContainerState{constants.StateCreated, constants.StateRunning} {
+		rootDir := newTestContainerWithStatus(t, status)
+
+		err := Resume(rootDir, "yacr-test-pause")
+		if err == nil || !strings.Contains(err.Error(), "unexpected status '"+string(status)+"'") {
+			t.Errorf("expected an unexpected status error, got: %v", err)
+		}
+	}
+}
+
+func TestResumeFailure(t *testing.T) {
+	rootDir := newTestContainerWithStatus(t, constants.StatePaused)
+
+	// The cgroup of the container does not exist.
+	if err := Resume(rootDir, "yacr-test-pause"); err == nil {
+		t.Errorf("expected an error")
+	}
+
+	c, err := container.Load(rootDir, "yacr-test-pause")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if !c.IsPaused() {
+		t.Errorf("expected container to be paused, got: %s", c.State.Status)
+	}
+}
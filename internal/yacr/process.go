@@ -0,0 +1,75 @@
+package yacr
+
+import (
+	"fmt"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/willdurand/containers/internal/yacr/seccomp"
+	"golang.org/x/sys/unix"
+)
+
+// setupProcess configures the current process according to the process
+// configuration right before the user-specified program is executed.
+//
+// Loading a seccomp filter requires either `CAP_SYS_ADMIN` or the
+// `no_new_privs` bit. When `NoNewPrivileges` is not requested, the filter is
+// therefore loaded before the user and capabilities are changed. Otherwise, it
+// is loaded last because it might restrict the syscalls needed by yacr. In
+// both cases, the filter is loaded before the caller reports success to the
+// host so that errors can be reported.
+func setupProcess(process *runtimespec.Process, seccompFilter *seccomp.Filter) error {
+	caps, err := newCapabilities(process.Capabilities)
+	if err != nil {
+		return err
+	}
+
+	// The scheduler and I/O priority are set while the process still has all
+	// its capabilities because raising them requires `CAP_SYS_NICE`.
+	if err := setScheduler(process.Scheduler); err != nil {
+		return err
+	}
+
+	if err := setIOPriority(process.IOPriority); err != nil {
+		return err
+	}
+
+	if !process.NoNewPrivileges {
+		if err := seccomp.Load(seccompFilter); err != nil {
+			return err
+		}
+	}
+
+	if err := caps.applyBoundingSet(); err != nil {
+		return err
+	}
+
+	// Keep the permitted capabilities when switching to a non-root user so
+	// that we can set the capabilities sets after.
+	if err := unix.Prctl(unix.PR_SET_KEEPCAPS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set keep caps: %w", err)
+	}
+
+	if err := setupUser(process.User); err != nil {
+		return err
+	}
+
+	if err := unix.Prctl(unix.PR_SET_KEEPCAPS, 0, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to clear keep caps: %w", err)
+	}
+
+	if err := caps.apply(); err != nil {
+		return err
+	}
+
+	if process.NoNewPrivileges {
+		if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+			return fmt.Errorf("failed to set no new privileges: %w", err)
+		}
+
+		if err := seccomp.Load(seccompFilter); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
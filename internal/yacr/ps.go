@@ -0,0 +1,174 @@
+package yacr
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/willdurand/containers/internal/yacr/cgroups"
+	"github.com/willdurand/containers/internal/yacr/container"
+)
+
+// Process represents a process running inside a container.
+type Process struct {
+	// PID is the process ID on the host.
+	PID int
+	// NSPID is the process ID in the PID namespace of the container.
+	NSPID   int
+	User    string
+	Command string
+}
+
+// Ps returns the processes running inside a container, sorted by host PID.
+func Ps(rootDir, containerId string) ([]Process, error) {
+	container, err := container.LoadWithBundleConfig(rootDir, containerId)
+	if err != nil {
+		return nil, err
+	}
+
+	if container.IsStopped() {
+		return nil, fmt.Errorf("container '%s' is not running", container.ID())
+	}
+
+	cgroup, err := container.Cgroup()
+	if err != nil {
+		return nil, err
+	}
+
+	pids, err := containerPids(container.State.Pid, cgroup)
+	if err != nil {
+		return nil, err
+	}
+	sort.Ints(pids)
+
+	var processes []Process
+	for _, pid := range pids {
+		process, err := readProcess(pid)
+		if err != nil {
+			// The process has likely exited in the meantime.
+			logrus.WithFields(logrus.Fields{
+				"pid":   pid,
+				"error": err,
+			}).Debug("failed to read process")
+			continue
+		}
+		processes = append(processes, process)
+	}
+
+	return processes, nil
+}
+
+// containerPids returns the PIDs of the processes in the cgroup of the
+// container. When the container does not have a cgroup, we look for the
+// processes in the PID namespace of the container process instead.
+func containerPids(pid int, cgroup *cgroups.Cgroup) ([]int, error) {
+	if cgroup.Exists() {
+		return cgroup.Processes()
+	}
+
+	pidns, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/pid", pid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PID namespace: %w", err)
+	}
+
+	// When the container shares the PID namespace of the host, we cannot walk
+	// the namespace so we only return the container process.
+	if selfns, err := os.Readlink("/proc/self/ns/pid"); err == nil && selfns == pidns {
+		return []int{pid}, nil
+	}
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	var pids []int
+	for _, entry := range entries {
+		p, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		if ns, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/pid", p)); err == nil && ns == pidns {
+			pids = append(pids, p)
+		}
+	}
+
+	return pids, nil
+}
+
+// readProcess reads the information of a process from `/proc`.
+func readProcess(pid int) (Process, error) {
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+
+	status, err := os.ReadFile(filepath.Join(dir, "status"))
+	if err != nil {
+		return Process{}, err
+	}
+
+	process, err := parseProcessStatus(status)
+	if err != nil {
+		return Process{}, err
+	}
+	process.PID = pid
+
+	if u, err := user.LookupId(process.User); err == nil {
+		process.User = u.Username
+	}
+
+	// Kernel threads and zombies have an empty command line, in which case we
+	// use the name of the process like `ps` does.
+	if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil && len(cmdline) > 0 {
+		process.Command = string(bytes.Join(bytes.Split(bytes.TrimRight(cmdline, "\x00"), []byte{0}), []byte(" ")))
+	} else {
+		process.Command = fmt.Sprintf("[%s]", process.Command)
+	}
+
+	return process, nil
+}
+
+// parseProcessStatus parses the content of a `/proc/<pid>/status` file. The
+// returned process has its `User` set to the real UID and its `Command` set to
+// the name of the process.
+func parseProcessStatus(data []byte) (Process, error) {
+	var process Process
+
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		fields := strings.Fields(value)
+
+		switch key {
+		case "Name":
+			process.Command = strings.TrimSpace(value)
+
+		case "Uid":
+			if len(fields) == 0 {
+				return process, fmt.Errorf("invalid Uid line: %s", line)
+			}
+			process.User = fields[0]
+
+		case "NSpid":
+			// The last value is the PID in the innermost PID namespace.
+			if len(fields) == 0 {
+				return process, fmt.Errorf("invalid NSpid line: %s", line)
+			}
+			nspid, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil {
+				return process, fmt.Errorf("invalid NSpid line: %w", err)
+			}
+			process.NSPID = nspid
+		}
+	}
+
+	return process, nil
+}
@@ -0,0 +1,45 @@
+package yacr
+
+import (
+	"testing"
+)
+
+func TestParseProcessStatus(t *testing.T) {
+	status := `Name:	sleep
+Umask:	0022
+State:	S (sleeping)
+Tgid:	1234
+NSpid:	1234	1
+Uid:	1000	1000	1000	1000
+Gid:	1000	1000	1000	1000
+`
+
+	process, err := parseProcessStatus([]byte(status))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if process.NSPID != 1 {
+		t.Errorf("expected NSPID: 1, got: %d", process.NSPID)
+	}
+
+	if process.User != "1000" {
+		t.Errorf("expected user: 1000, got: %s", process.User)
+	}
+
+	if process.Command != "sleep" {
+		t.Errorf("expected command: sleep, got: %s", process.Command)
+	}
+}
+
+func TestParseProcessStatusInvalid(t *testing.T) {
+	for _, status := range []string{
+		"NSpid:\n",
+		"NSpid:	1234	abc\n",
+		"Uid:\n",
+	} {
+		if _, err := parseProcessStatus([]byte(status)); err == nil {
+			t.Errorf("expected an error for: %q", status)
+		}
+	}
+}
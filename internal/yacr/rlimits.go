@@ -0,0 +1,89 @@
+package yacr
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+// rlimitTypes maps the rlimit types used in a runtime configuration to their
+// values.
+var rlimitTypes = map[string]int{
+	"RLIMIT_AS":         unix.RLIMIT_AS,
+	"RLIMIT_CORE":       unix.RLIMIT_CORE,
+	"RLIMIT_CPU":        unix.RLIMIT_CPU,
+	"RLIMIT_DATA":       unix.RLIMIT_DATA,
+	"RLIMIT_FSIZE":      unix.RLIMIT_FSIZE,
+	"RLIMIT_LOCKS":      unix.RLIMIT_LOCKS,
+	"RLIMIT_MEMLOCK":    unix.RLIMIT_MEMLOCK,
+	"RLIMIT_MSGQUEUE":   unix.RLIMIT_MSGQUEUE,
+	"RLIMIT_NICE":       unix.RLIMIT_NICE,
+	"RLIMIT_NOFILE":     unix.RLIMIT_NOFILE,
+	"RLIMIT_NPROC":      unix.RLIMIT_NPROC,
+	"RLIMIT_RSS":        unix.RLIMIT_RSS,
+	"RLIMIT_RTPRIO":     unix.RLIMIT_RTPRIO,
+	"RLIMIT_RTTIME":     unix.RLIMIT_RTTIME,
+	"RLIMIT_SIGPENDING": unix.RLIMIT_SIGPENDING,
+	"RLIMIT_STACK":      unix.RLIMIT_STACK,
+}
+
+// validateRlimits returns an error when a rlimit is invalid.
+func validateRlimits(rlimits []runtimespec.POSIXRlimit) error {
+	seen := make(map[string]bool)
+
+	for _, rlimit := range rlimits {
+		if _, ok := rlimitTypes[rlimit.Type]; !ok {
+			return fmt.Errorf("unknown rlimit type '%s'", rlimit.Type)
+		}
+
+		if seen[rlimit.Type] {
+			return fmt.Errorf("duplicate rlimit type '%s'", rlimit.Type)
+		}
+		seen[rlimit.Type] = true
+
+		if rlimit.Soft > rlimit.Hard {
+			return fmt.Errorf("invalid rlimit '%s': soft limit (%d) is greater than hard limit (%d)", rlimit.Type, rlimit.Soft, rlimit.Hard)
+		}
+	}
+
+	return nil
+}
+
+// setRlimits sets the rlimits of a process. This is done by the runtime (on
+// the host) because raising a hard limit requires privileges that the
+// container process might not have.
+func setRlimits(pid int, rlimits []runtimespec.POSIXRlimit) error {
+	if err := validateRlimits(rlimits); err != nil {
+		return err
+	}
+
+	for _, rlimit := range rlimits {
+		limit := unix.Rlimit{
+			Cur: rlimit.Soft,
+			Max: rlimit.Hard,
+		}
+
+		if err := unix.Prlimit(pid, rlimitTypes[rlimit.Type], &limit, nil); err != nil {
+			return fmt.Errorf("failed to set rlimit '%s': %w", rlimit.Type, err)
+		}
+	}
+
+	return nil
+}
+
+// setOOMScoreAdj writes the OOM score adjustment of a process when it is set.
+func setOOMScoreAdj(pid int, oomScoreAdj *int) error {
+	if oomScoreAdj == nil {
+		return nil
+	}
+
+	path := fmt.Sprintf("/proc/%d/oom_score_adj", pid)
+	if err := os.WriteFile(path, []byte(strconv.Itoa(*oomScoreAdj)), 0o644); err != nil {
+		return fmt.Errorf("failed to set oom score adj: %w", err)
+	}
+
+	return nil
+}
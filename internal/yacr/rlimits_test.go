@@ -0,0 +1,29 @@
+package yacr
+
+import (
+	"testing"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestValidateRlimits(t *testing.T) {
+	err := validateRlimits([]runtimespec.POSIXRlimit{
+		{Type: "RLIMIT_NOFILE", Hard: 1024, Soft: 1024},
+		{Type: "RLIMIT_NPROC", Hard: 100, Soft: 10},
+	})
+	if err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+}
+
+func TestValidateRlimitsInvalid(t *testing.T) {
+	for _, rlimits := range [][]runtimespec.POSIXRlimit{
+		{{Type: "RLIMIT_UNKNOWN", Hard: 1, Soft: 1}},
+		{{Type: "RLIMIT_NOFILE", Hard: 1, Soft: 2}},
+		{{Type: "RLIMIT_NOFILE", Hard: 1, Soft: 1}, {Type: "RLIMIT_NOFILE", Hard: 2, Soft: 2}},
+	} {
+		if err := validateRlimits(rlimits); err == nil {
+			t.Errorf("expected an error for: %+v", rlimits)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package yacr
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/willdurand/containers/internal/runtime"
+	"github.com/willdurand/containers/internal/yacr/container"
+	"golang.org/x/sys/unix"
+)
+
+type RunOpts struct {
+	CreateOpts
+	Keep bool
+}
+
+// Run creates and starts a container, then waits for its process and deletes
+// it, unless `Keep` is set. It returns the exit status of the container
+// process.
+//
+// When the container should create a terminal and no console socket is given,
+// the terminal is attached to the standard streams of the current process.
+//
+// The `poststop` hooks are executed once the container process has exited,
+// even when `Keep` is set. In this case, `yacr delete` does not execute them
+// again.
+func Run(rootDir string, opts RunOpts) (int, error) {
+	if _, err := os.Stat(filepath.Join(rootDir, opts.ID)); err == nil {
+		return -1, fmt.Errorf("container '%s' already exists", opts.ID)
+	}
+
+	spec, err := runtime.LoadSpec(opts.Bundle)
+	if err != nil {
+		return -1, err
+	}
+
+	var consoleListener net.Listener
+	if spec.Process != nil && spec.Process.Terminal && opts.ConsoleSocket == "" {
+		dir, err := os.MkdirTemp("", "yacr-run-")
+		if err != nil {
+			return -1, err
+		}
+		defer os.RemoveAll(dir)
+
+		opts.ConsoleSocket = filepath.Join(dir, "console.sock")
+		consoleListener, err = net.Listen("unix", opts.ConsoleSocket)
+		if err != nil {
+			return -1, fmt.Errorf("listen (console socket): %w", err)
+		}
+		defer consoleListener.Close()
+	}
+
+	// The container process is a child of the current process, which is why we
+	// can wait for it below.
+	if err := Create(rootDir, opts.CreateOpts); err != nil {
+		destroy(rootDir, opts.ID)
+		return -1, err
+	}
+
+	c, err := container.Load(rootDir, opts.ID)
+	if err != nil {
+		destroy(rootDir, opts.ID)
+		return -1, err
+	}
+	pid := c.State.Pid
+
+	detachConsole := func() {}
+	if consoleListener != nil {
+		ptm, err := receiveConsole(consoleListener)
+		if err != nil {
+			destroy(rootDir, opts.ID)
+			return -1, err
+		}
+		defer ptm.Close()
+
+		detachConsole, err = attachConsole(ptm)
+		if err != nil {
+			destroy(rootDir, opts.ID)
+			return -1, err
+		}
+	}
+
+	if err := Start(rootDir, opts.ID); err != nil {
+		destroy(rootDir, opts.ID)
+		detachConsole()
+		return -1, err
+	}
+
+	exitStatus, err := waitProcess(pid)
+	detachConsole()
+	if err != nil {
+		destroy(rootDir, opts.ID)
+		return -1, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":         opts.ID,
+		"exitStatus": exitStatus,
+	}).Debug("container process exited")
+
+	if opts.Keep {
+		c, err := container.LoadWithBundleConfig(rootDir, opts.ID)
+		if err != nil {
+			return exitStatus, err
+		}
+
+		if err := c.ExecutePoststopHooks(); err != nil {
+			return exitStatus, err
+		}
+	} else {
+		if err := Delete(rootDir, opts.ID, false); err != nil {
+			return exitStatus, err
+		}
+	}
+
+	return exitStatus, nil
+}
+
+// destroy kills the process of a container that could not be run and force
+// deletes the container.
+func destroy(rootDir, containerId string) {
+	if c, err := container.Load(rootDir, containerId); err == nil && c.State.Pid != 0 {
+		unix.Kill(c.State.Pid, unix.SIGKILL)
+		unix.Wait4(c.State.Pid, nil, 0, nil)
+	}
+
+	if err := Delete(rootDir, containerId, true); err != nil {
+		logrus.WithError(err).WithField("id", containerId).Debug("failed to delete container")
+	}
+}
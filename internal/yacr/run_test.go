@@ -0,0 +1,34 @@
+package yacr
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunExistingContainer(t *testing.T) {
+	rootDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(rootDir, "test-id"), 0o755); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	_, err := Run(rootDir, RunOpts{CreateOpts: CreateOpts{ID: "test-id", Bundle: t.TempDir()}})
+
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected an error, got: %v", err)
+	}
+}
+
+func TestRunInvalidBundle(t *testing.T) {
+	rootDir := t.TempDir()
+
+	_, err := Run(rootDir, RunOpts{CreateOpts: CreateOpts{ID: "test-id", Bundle: t.TempDir()}})
+	if err == nil {
+		t.Errorf("expected an error")
+	}
+
+	if _, err := os.Stat(filepath.Join(rootDir, "test-id")); !os.IsNotExist(err) {
+		t.Errorf("expected no container directory, got: %v", err)
+	}
+}
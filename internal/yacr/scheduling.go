@@ -0,0 +1,169 @@
+package yacr
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+// See: https://man7.org/linux/man-pages/man2/ioprio_set.2.html
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+	ioprioMaxLevel   = 7
+)
+
+var ioprioClasses = map[runtimespec.IOPriorityClass]int{
+	runtimespec.IOPRIO_CLASS_RT:   1,
+	runtimespec.IOPRIO_CLASS_BE:   2,
+	runtimespec.IOPRIO_CLASS_IDLE: 3,
+}
+
+// See: https://man7.org/linux/man-pages/man7/sched.7.html
+var schedPolicies = map[runtimespec.LinuxSchedulerPolicy]uint32{
+	runtimespec.SchedOther:    0,
+	runtimespec.SchedFIFO:     1,
+	runtimespec.SchedRR:       2,
+	runtimespec.SchedBatch:    3,
+	runtimespec.SchedIdle:     5,
+	runtimespec.SchedDeadline: 6,
+}
+
+var schedFlags = map[runtimespec.LinuxSchedulerFlag]uint64{
+	runtimespec.SchedFlagResetOnFork:  0x01,
+	runtimespec.SchedFlagReclaim:      0x02,
+	runtimespec.SchedFlagDLOverrun:    0x04,
+	runtimespec.SchedFlagKeepPolicy:   0x08,
+	runtimespec.SchedFlagKeepParams:   0x10,
+	runtimespec.SchedFlagUtilClampMin: 0x20,
+	runtimespec.SchedFlagUtilClampMax: 0x40,
+}
+
+// schedAttr is the `sched_attr` struct used by sched_setattr(2).
+type schedAttr struct {
+	size     uint32
+	policy   uint32
+	flags    uint64
+	nice     int32
+	priority uint32
+	runtime  uint64
+	deadline uint64
+	period   uint64
+}
+
+// validateIOPriority checks the I/O priority of a process.
+func validateIOPriority(prio *runtimespec.LinuxIOPriority) error {
+	if prio == nil {
+		return nil
+	}
+
+	if _, ok := ioprioClasses[prio.Class]; !ok {
+		return fmt.Errorf("unknown I/O priority class '%s'", prio.Class)
+	}
+
+	if prio.Priority < 0 || prio.Priority > ioprioMaxLevel {
+		return fmt.Errorf("invalid I/O priority %d", prio.Priority)
+	}
+
+	return nil
+}
+
+// setIOPriority sets the I/O priority of the current thread, which is
+// inherited by the program executed with exec(3).
+func setIOPriority(prio *runtimespec.LinuxIOPriority) error {
+	if prio == nil {
+		return nil
+	}
+
+	if err := validateIOPriority(prio); err != nil {
+		return err
+	}
+
+	value := ioprioClasses[prio.Class]<<ioprioClassShift | prio.Priority
+	if _, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, 0, uintptr(value)); errno != 0 {
+		return fmt.Errorf("failed to set I/O priority: %w", errno)
+	}
+
+	return nil
+}
+
+// validateScheduler checks the scheduling policy and attributes of a process.
+func validateScheduler(scheduler *runtimespec.Scheduler) error {
+	if scheduler == nil {
+		return nil
+	}
+
+	if _, ok := schedPolicies[scheduler.Policy]; !ok {
+		return fmt.Errorf("unsupported scheduling policy '%s'", scheduler.Policy)
+	}
+
+	for _, flag := range scheduler.Flags {
+		if _, ok := schedFlags[flag]; !ok {
+			return fmt.Errorf("unknown scheduling flag '%s'", flag)
+		}
+	}
+
+	if scheduler.Nice < -20 || scheduler.Nice > 19 {
+		return fmt.Errorf("invalid nice value %d", scheduler.Nice)
+	}
+
+	switch scheduler.Policy {
+	case runtimespec.SchedFIFO, runtimespec.SchedRR:
+		if scheduler.Priority < 1 || scheduler.Priority > 99 {
+			return fmt.Errorf("invalid priority %d for policy '%s'", scheduler.Priority, scheduler.Policy)
+		}
+	default:
+		if scheduler.Priority != 0 {
+			return fmt.Errorf("priority must be 0 for policy '%s'", scheduler.Policy)
+		}
+	}
+
+	if scheduler.Policy == runtimespec.SchedDeadline {
+		period := scheduler.Period
+		if period == 0 {
+			period = scheduler.Deadline
+		}
+
+		if scheduler.Runtime == 0 || scheduler.Runtime > scheduler.Deadline || scheduler.Deadline > period {
+			return errors.New("deadline scheduling requires runtime <= deadline <= period")
+		}
+	} else if scheduler.Runtime != 0 || scheduler.Deadline != 0 || scheduler.Period != 0 {
+		return fmt.Errorf("runtime, deadline and period are not supported by policy '%s'", scheduler.Policy)
+	}
+
+	return nil
+}
+
+// setScheduler sets the scheduling policy and attributes of the current
+// thread, which are inherited by the program executed with exec(3).
+func setScheduler(scheduler *runtimespec.Scheduler) error {
+	if scheduler == nil {
+		return nil
+	}
+
+	if err := validateScheduler(scheduler); err != nil {
+		return err
+	}
+
+	attr := schedAttr{
+		policy:   schedPolicies[scheduler.Policy],
+		nice:     scheduler.Nice,
+		priority: uint32(scheduler.Priority),
+		runtime:  scheduler.Runtime,
+		deadline: scheduler.Deadline,
+		period:   scheduler.Period,
+	}
+	attr.size = uint32(unsafe.Sizeof(attr))
+	for _, flag := range scheduler.Flags {
+		attr.flags |= schedFlags[flag]
+	}
+
+	if _, _, errno := unix.Syscall(unix.SYS_SCHED_SETATTR, 0, uintptr(unsafe.Pointer(&attr)), 0); errno != 0 {
+		return fmt.Errorf("failed to set scheduler: %w", errno)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package seccomp
+
+import runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+
+const (
+	nativeArch = runtimespec.ArchX86_64
+	// AUDIT_ARCH_X86_64
+	auditArch uint32 = 0xc000003e
+	// x32SyscallBit is set in the numbers of the x32 ABI syscalls, which share
+	// the audit architecture of x86_64.
+	x32SyscallBit uint32 = 0x40000000
+)
+
+// abis contains the ABIs that a process can use on x86_64, starting with the
+// native one.
+var abis = []abi{
+	{arch: nativeArch, auditArch: auditArch, syscalls: syscallNumbers},
+	{arch: runtimespec.ArchX32, auditArch: auditArch, syscalls: x32SyscallNumbers},
+	// AUDIT_ARCH_I386
+	{arch: runtimespec.ArchX86, auditArch: 0x40000003, syscalls: x86SyscallNumbers},
+}
+
+// x32SyscallNumbers is the syscall table of the x32 ABI. Most syscalls have
+// the same numbers as on x86_64 (with `x32SyscallBit` set) but the ones that
+// take (or return) structs with pointers have their own numbers.
+//
+// See: https://github.com/torvalds/linux/blob/master/arch/x86/entry/syscalls/syscall_64.tbl
+var x32SyscallNumbers = map[string]int{}
+
+func init() {
+	for name, nr := range syscallNumbers {
+		x32SyscallNumbers[name] = nr
+	}
+
+	for i, name := range []string{
+		"rt_sigaction", "rt_sigreturn", "ioctl", "readv", "writev", "recvfrom",
+		"sendmsg", "recvmsg", "execve", "ptrace", "rt_sigpending",
+		"rt_sigtimedwait", "rt_sigqueueinfo", "sigaltstack", "timer_create",
+		"mq_notify", "kexec_load", "waitid", "set_robust_list", "get_robust_list",
+		"vmsplice", "move_pages", "preadv", "pwritev", "rt_tgsigqueueinfo",
+		"recvmmsg", "sendmmsg", "process_vm_readv", "process_vm_writev",
+		"setsockopt", "getsockopt", "io_setup", "io_submit", "execveat",
+		"preadv2", "pwritev2",
+	} {
+		x32SyscallNumbers[name] = 512 + i
+	}
+
+	for name, nr := range x32SyscallNumbers {
+		x32SyscallNumbers[name] = nr | int(x32SyscallBit)
+	}
+}
@@ -0,0 +1,34 @@
+package seccomp
+
+import runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+
+const (
+	nativeArch = runtimespec.ArchAARCH64
+	// AUDIT_ARCH_AARCH64
+	auditArch     uint32 = 0xc00000b7
+	x32SyscallBit uint32 = 0
+)
+
+// abis contains the ABIs that a process can use on arm64, starting with the
+// native one.
+var abis = []abi{
+	{arch: nativeArch, auditArch: auditArch, syscalls: syscallNumbers},
+	// AUDIT_ARCH_ARM
+	{arch: runtimespec.ArchARM, auditArch: 0x40000028, syscalls: armSyscallNumbers},
+}
+
+func init() {
+	// The kernel names this syscall `newfstatat` on arm64 but the generated
+	// table uses the generic name.
+	syscallNumbers["newfstatat"] = syscallNumbers["fstatat"]
+
+	// The ARM private syscalls are not part of the generated table.
+	//
+	// See: https://github.com/torvalds/linux/blob/master/arch/arm/include/uapi/asm/unistd.h
+	armSyscallNumbers["breakpoint"] = 0x0f0001
+	armSyscallNumbers["cacheflush"] = 0x0f0002
+	armSyscallNumbers["usr26"] = 0x0f0003
+	armSyscallNumbers["usr32"] = 0x0f0004
+	armSyscallNumbers["set_tls"] = 0x0f0005
+	armSyscallNumbers["get_tls"] = 0x0f0006
+}
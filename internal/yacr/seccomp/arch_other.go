@@ -0,0 +1,13 @@
+//go:build !amd64 && !arm64
+
+package seccomp
+
+const (
+	nativeArch           = ""
+	auditArch     uint32 = 0
+	x32SyscallBit uint32 = 0
+)
+
+var syscallNumbers = map[string]int{}
+
+var abis = []abi{}
@@ -0,0 +1,130 @@
+package seccomp
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// label identifies a position in a BPF program, which can be used as a jump
+// target before the position is known.
+type label int
+
+// next is a special label that represents the next instruction.
+const next label = -1
+
+type fixup struct {
+	pos    int
+	jt, jf label
+	// always is set for unconditional jumps, which use `jt` as target.
+	always bool
+}
+
+// assembler is a (very) small classic BPF assembler that resolves conditional
+// jumps to labels.
+type assembler struct {
+	filter []unix.SockFilter
+	labels []int
+	fixups []fixup
+}
+
+func (a *assembler) newLabel() label {
+	a.labels = append(a.labels, -1)
+	return label(len(a.labels) - 1)
+}
+
+// bind sets the position of a label to the next instruction.
+func (a *assembler) bind(l label) {
+	a.labels[l] = len(a.filter)
+}
+
+func (a *assembler) stmt(code uint16, k uint32) {
+	a.filter = append(a.filter, unix.SockFilter{Code: code, K: k})
+}
+
+func (a *assembler) jump(code uint16, k uint32, jt, jf label) {
+	a.fixups = append(a.fixups, fixup{pos: len(a.filter), jt: jt, jf: jf})
+	a.filter = append(a.filter, unix.SockFilter{Code: unix.BPF_JMP | code | unix.BPF_K, K: k})
+}
+
+// ja appends an unconditional jump. Unlike conditional jumps, its offset is
+// not limited to 255 instructions.
+func (a *assembler) ja(l label) {
+	a.fixups = append(a.fixups, fixup{pos: len(a.filter), jt: l, always: true})
+	a.filter = append(a.filter, unix.SockFilter{Code: unix.BPF_JMP | unix.BPF_JA})
+}
+
+// loadAbs loads a 32-bit word of the `seccomp_data` struct into the
+// accumulator.
+func (a *assembler) loadAbs(offset uint32) {
+	a.stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, offset)
+}
+
+func (a *assembler) ret(value uint32) {
+	a.stmt(unix.BPF_RET|unix.BPF_K, value)
+}
+
+// assemble resolves the jumps and returns the BPF program.
+func (a *assembler) assemble() ([]unix.SockFilter, error) {
+	for _, f := range a.fixups {
+		if f.always {
+			offset, err := a.target(f.pos, f.jt)
+			if err != nil {
+				return nil, err
+			}
+			a.filter[f.pos].K = uint32(offset)
+			continue
+		}
+
+		jt, err := a.offset(f.pos, f.jt)
+		if err != nil {
+			return nil, err
+		}
+		jf, err := a.offset(f.pos, f.jf)
+		if err != nil {
+			return nil, err
+		}
+
+		a.filter[f.pos].Jt = jt
+		a.filter[f.pos].Jf = jf
+	}
+
+	if len(a.filter) > unix.BPF_MAXINSNS {
+		return nil, fmt.Errorf("seccomp program is too large (%d instructions)", len(a.filter))
+	}
+
+	return a.filter, nil
+}
+
+func (a *assembler) offset(pos int, l label) (uint8, error) {
+	offset, err := a.target(pos, l)
+	if err != nil {
+		return 0, err
+	}
+
+	if offset > 255 {
+		return 0, fmt.Errorf("invalid jump offset %d", offset)
+	}
+
+	return uint8(offset), nil
+}
+
+// target returns the number of instructions to skip to jump from `pos` to a
+// label. Only forward jumps are allowed.
+func (a *assembler) target(pos int, l label) (int, error) {
+	if l == next {
+		return 0, nil
+	}
+
+	target := a.labels[l]
+	if target < 0 {
+		return 0, fmt.Errorf("unbound label %d", l)
+	}
+
+	offset := target - pos - 1
+	if offset < 0 {
+		return 0, fmt.Errorf("invalid jump offset %d", offset)
+	}
+
+	return offset, nil
+}
@@ -0,0 +1,76 @@
+package seccomp
+
+import (
+	"sort"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/opencontainers/runtime-spec/specs-go/features"
+)
+
+// specActions contains the actions defined by the runtime spec.
+var specActions = []runtimespec.LinuxSeccompAction{
+	runtimespec.ActKill,
+	runtimespec.ActKillProcess,
+	runtimespec.ActKillThread,
+	runtimespec.ActTrap,
+	runtimespec.ActErrno,
+	runtimespec.ActTrace,
+	runtimespec.ActAllow,
+	runtimespec.ActLog,
+	runtimespec.ActNotify,
+}
+
+// specOperators contains the operators defined by the runtime spec.
+var specOperators = []runtimespec.LinuxSeccompOperator{
+	runtimespec.OpNotEqual,
+	runtimespec.OpLessThan,
+	runtimespec.OpLessEqual,
+	runtimespec.OpEqualTo,
+	runtimespec.OpGreaterEqual,
+	runtimespec.OpGreaterThan,
+	runtimespec.OpMaskedEqual,
+}
+
+// Features returns the seccomp features supported by yacr. Actions and
+// operators are those accepted by the compiler.
+func Features() *features.Seccomp {
+	enabled := nativeArch != ""
+	if !enabled {
+		return &features.Seccomp{Enabled: &enabled}
+	}
+
+	var actions []string
+	for _, action := range specActions {
+		if _, err := actionValue(action, nil); err == nil {
+			actions = append(actions, string(action))
+		}
+	}
+
+	var operators []string
+	for _, op := range specOperators {
+		a := new(assembler)
+		if err := compileArg(a, runtimespec.LinuxSeccompArg{Op: op}, a.newLabel()); err == nil {
+			operators = append(operators, string(op))
+		}
+	}
+
+	var flags []string
+	for flag := range flagValues {
+		flags = append(flags, string(flag))
+	}
+	sort.Strings(flags)
+
+	var archs []string
+	for _, abi := range abis {
+		archs = append(archs, string(abi.arch))
+	}
+
+	return &features.Seccomp{
+		Enabled:        &enabled,
+		Actions:        actions,
+		Operators:      operators,
+		Archs:          archs,
+		KnownFlags:     flags,
+		SupportedFlags: flags,
+	}
+}
@@ -0,0 +1,81 @@
+//go:build ignore
+
+// This program generates the syscall tables used by the seccomp package from
+// the `zsysnum_linux_<arch>.go` files of `golang.org/x/sys/unix`.
+//
+// Usage: go run mksyscalls.go [-abi <name>] <arch> <path/to/zsysnum_linux_arch.go>
+//
+// The `-abi` flag generates the table of a compat ABI (e.g., x86 on amd64),
+// which is named `<name>SyscallNumbers` and only built on `<arch>`.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var re = regexp.MustCompile(`^\s+SYS_([A-Z0-9_]+)\s+=\s+(\d+)$`)
+
+// notSyscalls contains the `SYS_*` constants that are not syscalls.
+var notSyscalls = map[string]bool{
+	// The mask of the (OABI) syscall numbers on arm.
+	"SYSCALL_MASK": true,
+}
+
+func main() {
+	abi := flag.String("abi", "", "name of a compat ABI")
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		log.Fatal("usage: go run mksyscalls.go [-abi <name>] <arch> <zsysnum file>")
+	}
+	arch, input := flag.Arg(0), flag.Arg(1)
+
+	command, varName, output := arch, "syscallNumbers", fmt.Sprintf("zsyscalls_linux_%s.go", arch)
+	if *abi != "" {
+		command = fmt.Sprintf("-abi %s %s", *abi, arch)
+		varName = *abi + "SyscallNumbers"
+		output = fmt.Sprintf("zsyscalls_%s_linux_%s.go", *abi, arch)
+	}
+
+	f, err := os.Open(input)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by \"go run mksyscalls.go %s\"; DO NOT EDIT.\n\n", command)
+	fmt.Fprint(&b, "package seccomp\n\n")
+	fmt.Fprintf(&b, "var %s = map[string]int{\n", varName)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		m := re.FindStringSubmatch(scanner.Text())
+		if m == nil || notSyscalls[m[1]] {
+			continue
+		}
+		fmt.Fprintf(&b, "\t%q: %s,\n", strings.ToLower(m[1]), m[2])
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Fprint(&b, "}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(output, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,374 @@
+// Package seccomp compiles the seccomp configuration of a container into a
+// BPF program and loads it, without relying on libseccomp.
+//
+// The rules are compiled for the native architecture and for each of the
+// `architectures` of the configuration that a process can use on this machine
+// (e.g., x86 and x32 on x86_64). Syscalls made with another ABI kill the
+// process, which is also what libseccomp does for the architectures that are
+// not part of a filter.
+package seccomp
+
+import (
+	"errors"
+	"fmt"
+	goruntime "runtime"
+	"unsafe"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// Filter is a compiled seccomp filter.
+type Filter struct {
+	Program []unix.SockFilter
+	Flags   uint
+}
+
+// See: https://man7.org/linux/man-pages/man2/seccomp.2.html
+const (
+	retKillProcess uint32 = 0x80000000
+	retKillThread  uint32 = 0x00000000
+	retTrap        uint32 = 0x00030000
+	retErrno       uint32 = 0x00050000
+	retTrace       uint32 = 0x7ff00000
+	retLog         uint32 = 0x7ffc0000
+	retAllow       uint32 = 0x7fff0000
+
+	setModeFilter uintptr = 1
+
+	// Offsets of the `seccomp_data` struct fields.
+	offsetNr   uint32 = 0
+	offsetArch uint32 = 4
+	offsetArgs uint32 = 16
+
+	maxArgs uint = 6
+)
+
+var flagValues = map[runtimespec.LinuxSeccompFlag]uint{
+	"SECCOMP_FILTER_FLAG_TSYNC":      1,
+	"SECCOMP_FILTER_FLAG_LOG":        2,
+	"SECCOMP_FILTER_FLAG_SPEC_ALLOW": 4,
+}
+
+// Compile converts a seccomp configuration into a filter. It returns `nil`
+// when there is no configuration.
+//
+// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/config-linux.md#seccomp
+func Compile(config *runtimespec.LinuxSeccomp) (*Filter, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	if nativeArch == "" {
+		return nil, fmt.Errorf("seccomp is not supported on %s", goruntime.GOARCH)
+	}
+
+	// A seccomp notify listener would have to receive the notification file
+	// descriptor, which is not supported.
+	if config.ListenerPath != "" {
+		return nil, errors.New("seccomp listener is not supported")
+	}
+
+	defaultAction, err := actionValue(config.DefaultAction, config.DefaultErrnoRet)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := new(Filter)
+	for _, flag := range config.Flags {
+		value, ok := flagValues[flag]
+		if !ok {
+			return nil, fmt.Errorf("unsupported seccomp flag '%s'", flag)
+		}
+		filter.Flags |= value
+	}
+
+	var rules []rule
+	for _, syscall := range config.Syscalls {
+		action, err := actionValue(syscall.Action, syscall.ErrnoRet)
+		if err != nil {
+			return nil, err
+		}
+
+		// Rules with the default action do not change anything.
+		if action == defaultAction {
+			continue
+		}
+
+		for _, arg := range syscall.Args {
+			if arg.Index >= maxArgs {
+				return nil, fmt.Errorf("invalid seccomp argument index %d", arg.Index)
+			}
+		}
+
+		rules = append(rules, rule{syscall: syscall, action: action})
+	}
+
+	selected := selectABIs(config.Architectures)
+	blocks := make([]label, len(selected))
+	var x32Block label
+	hasX32 := false
+
+	a := new(assembler)
+
+	// Jump to the block of rules of the ABI used by the syscall, or kill the
+	// process when this ABI is not part of the filter. x32 syscalls are
+	// dispatched from the x86_64 block because both ABIs share the same audit
+	// architecture.
+	a.loadAbs(offsetArch)
+	for i, abi := range selected {
+		blocks[i] = a.newLabel()
+		if abi.arch == runtimespec.ArchX32 {
+			x32Block, hasX32 = blocks[i], true
+			continue
+		}
+
+		skip := a.newLabel()
+		a.jump(unix.BPF_JEQ, abi.auditArch, next, skip)
+		a.ja(blocks[i])
+		a.bind(skip)
+	}
+	a.ret(retKillProcess)
+
+	for i, abi := range selected {
+		a.bind(blocks[i])
+		a.loadAbs(offsetNr)
+
+		if abi.arch == nativeArch && x32SyscallBit != 0 {
+			nrOk := a.newLabel()
+			a.jump(unix.BPF_JGE, x32SyscallBit, next, nrOk)
+			if hasX32 {
+				a.ja(x32Block)
+			} else {
+				a.ret(retKillProcess)
+			}
+			a.bind(nrOk)
+		}
+
+		if err := compileRules(a, abi, rules); err != nil {
+			return nil, err
+		}
+
+		a.ret(defaultAction)
+	}
+
+	program, err := a.assemble()
+	if err != nil {
+		return nil, err
+	}
+	filter.Program = program
+
+	return filter, nil
+}
+
+// Load installs a seccomp filter for the current process. Once loaded, the
+// filter cannot be removed.
+func Load(filter *Filter) error {
+	if filter == nil {
+		return nil
+	}
+
+	prog := unix.SockFprog{
+		Len:    uint16(len(filter.Program)),
+		Filter: &filter.Program[0],
+	}
+
+	_, _, errno := unix.Syscall(unix.SYS_SECCOMP, setModeFilter, uintptr(filter.Flags), uintptr(unsafe.Pointer(&prog)))
+	if errno != 0 {
+		return fmt.Errorf("failed to load seccomp filter: %w", errno)
+	}
+
+	return nil
+}
+
+// abi is a syscall ABI that can be part of a filter.
+type abi struct {
+	arch      runtimespec.Arch
+	auditArch uint32
+	syscalls  map[string]int
+}
+
+// rule is a syscall rule of a configuration with its compiled action.
+type rule struct {
+	syscall runtimespec.LinuxSyscall
+	action  uint32
+}
+
+// selectABIs returns the native ABI followed by the ABIs of the given
+// architectures that are supported on this machine.
+func selectABIs(architectures []runtimespec.Arch) []abi {
+	selected := []abi{abis[0]}
+
+	for _, arch := range architectures {
+		if arch == nativeArch {
+			continue
+		}
+
+		found := false
+		for _, abi := range abis[1:] {
+			if abi.arch == arch {
+				selected = append(selected, abi)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			// Processes cannot use this architecture on this machine.
+			logrus.WithField("arch", arch).Debug("seccomp: ignoring unsupported architecture")
+		}
+	}
+
+	return selected
+}
+
+// compileRules appends the rules of an ABI to the program. It expects the
+// syscall number to be in the accumulator.
+func compileRules(a *assembler, abi abi, rules []rule) error {
+	nrLoaded := true
+
+	for _, r := range rules {
+		for _, name := range r.syscall.Names {
+			nr, ok := abi.syscalls[name]
+			if !ok {
+				// Like runc, we ignore the syscalls that are unknown so that the same
+				// profile can be used on different architectures and kernels.
+				logrus.WithFields(logrus.Fields{
+					"arch":    abi.arch,
+					"syscall": name,
+				}).Debug("seccomp: ignoring unknown syscall")
+				continue
+			}
+
+			if err := compileRule(a, uint32(nr), r.syscall.Args, r.action, nrLoaded); err != nil {
+				return err
+			}
+
+			// The accumulator still contains the syscall number at the end of a rule
+			// without arguments.
+			nrLoaded = len(r.syscall.Args) == 0
+		}
+	}
+
+	return nil
+}
+
+// compileRule appends the instructions of a rule to the program. The syscall
+// number is checked first (and loaded when `nrLoaded` is false), then each
+// argument condition. When everything matches, the rule action is returned.
+// Otherwise, the program continues with the next rule.
+func compileRule(a *assembler, nr uint32, args []runtimespec.LinuxSeccompArg, action uint32, nrLoaded bool) error {
+	end := a.newLabel()
+
+	if !nrLoaded {
+		a.loadAbs(offsetNr)
+	}
+	a.jump(unix.BPF_JEQ, nr, next, end)
+
+	for _, arg := range args {
+		if err := compileArg(a, arg, end); err != nil {
+			return err
+		}
+	}
+
+	a.ret(action)
+	a.bind(end)
+
+	return nil
+}
+
+// compileArg appends the instructions that compare a 64-bit syscall argument.
+// Classic BPF only supports 32-bit values so we compare the upper half first
+// and then the lower half. The program jumps to `fail` when the condition is
+// not met.
+func compileArg(a *assembler, arg runtimespec.LinuxSeccompArg, fail label) error {
+	// Both amd64 and arm64 are little-endian architectures.
+	lo := offsetArgs + uint32(arg.Index)*8
+	hi := lo + 4
+
+	valueHi, valueLo := uint32(arg.Value>>32), uint32(arg.Value)
+	ok := a.newLabel()
+
+	switch arg.Op {
+	case runtimespec.OpEqualTo:
+		a.loadAbs(hi)
+		a.jump(unix.BPF_JEQ, valueHi, next, fail)
+		a.loadAbs(lo)
+		a.jump(unix.BPF_JEQ, valueLo, next, fail)
+	case runtimespec.OpNotEqual:
+		a.loadAbs(hi)
+		a.jump(unix.BPF_JEQ, valueHi, next, ok)
+		a.loadAbs(lo)
+		a.jump(unix.BPF_JEQ, valueLo, fail, next)
+	case runtimespec.OpGreaterThan, runtimespec.OpGreaterEqual:
+		a.loadAbs(hi)
+		a.jump(unix.BPF_JGT, valueHi, ok, next)
+		a.jump(unix.BPF_JEQ, valueHi, next, fail)
+		a.loadAbs(lo)
+		code := uint16(unix.BPF_JGT)
+		if arg.Op == runtimespec.OpGreaterEqual {
+			code = unix.BPF_JGE
+		}
+		a.jump(code, valueLo, next, fail)
+	case runtimespec.OpLessThan, runtimespec.OpLessEqual:
+		a.loadAbs(hi)
+		a.jump(unix.BPF_JGT, valueHi, fail, next)
+		a.jump(unix.BPF_JEQ, valueHi, next, ok)
+		a.loadAbs(lo)
+		code := uint16(unix.BPF_JGE)
+		if arg.Op == runtimespec.OpLessEqual {
+			code = unix.BPF_JGT
+		}
+		a.jump(code, valueLo, fail, next)
+	case runtimespec.OpMaskedEqual:
+		// `value` is the mask and `valueTwo` is the expected value.
+		a.loadAbs(hi)
+		a.stmt(unix.BPF_ALU|unix.BPF_AND|unix.BPF_K, valueHi)
+		a.jump(unix.BPF_JEQ, uint32(arg.ValueTwo>>32), next, fail)
+		a.loadAbs(lo)
+		a.stmt(unix.BPF_ALU|unix.BPF_AND|unix.BPF_K, valueLo)
+		a.jump(unix.BPF_JEQ, uint32(arg.ValueTwo), next, fail)
+	default:
+		return fmt.Errorf("unsupported seccomp operator '%s'", arg.Op)
+	}
+
+	a.bind(ok)
+
+	return nil
+}
+
+// actionValue returns the value of an action. `errnoRet` is the error number
+// returned by the `SCMP_ACT_ERRNO` and `SCMP_ACT_TRACE` actions (`EPERM` by
+// default).
+func actionValue(action runtimespec.LinuxSeccompAction, errnoRet *uint) (uint32, error) {
+	errno := uint32(unix.EPERM)
+	if errnoRet != nil {
+		if action != runtimespec.ActErrno && action != runtimespec.ActTrace {
+			return 0, fmt.Errorf("errnoRet is not supported with seccomp action '%s'", action)
+		}
+		if *errnoRet > 0xffff {
+			return 0, fmt.Errorf("invalid seccomp errnoRet %d", *errnoRet)
+		}
+		errno = uint32(*errnoRet)
+	}
+
+	switch action {
+	case runtimespec.ActKill, runtimespec.ActKillThread:
+		return retKillThread, nil
+	case runtimespec.ActKillProcess:
+		return retKillProcess, nil
+	case runtimespec.ActTrap:
+		return retTrap, nil
+	case runtimespec.ActErrno:
+		return retErrno | errno, nil
+	case runtimespec.ActTrace:
+		return retTrace | errno, nil
+	case runtimespec.ActAllow:
+		return retAllow, nil
+	case runtimespec.ActLog:
+		return retLog, nil
+	}
+
+	return 0, fmt.Errorf("unsupported seccomp action '%s'", action)
+}
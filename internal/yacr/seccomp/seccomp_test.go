@@ -0,0 +1,363 @@
+package seccomp
+
+import (
+	"encoding/binary"
+	"testing"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+// run executes a (classic) BPF program against a `seccomp_data` struct and
+// returns the value of the `ret` instruction.
+func run(t *testing.T, program []unix.SockFilter, arch uint32, nr uint32, args [6]uint64) uint32 {
+	data := make([]byte, 64)
+	binary.LittleEndian.PutUint32(data[offsetNr:], nr)
+	binary.LittleEndian.PutUint32(data[offsetArch:], arch)
+	for i, arg := range args {
+		binary.LittleEndian.PutUint64(data[offsetArgs+uint32(i)*8:], arg)
+	}
+
+	var acc uint32
+	for pc := 0; pc < len(program); pc++ {
+		insn := program[pc]
+
+		switch insn.Code {
+		case unix.BPF_LD | unix.BPF_W | unix.BPF_ABS:
+			acc = binary.LittleEndian.Uint32(data[insn.K:])
+		case unix.BPF_ALU | unix.BPF_AND | unix.BPF_K:
+			acc &= insn.K
+		case unix.BPF_RET | unix.BPF_K:
+			return insn.K
+		case unix.BPF_JMP | unix.BPF_JA:
+			pc += int(insn.K)
+		case unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K,
+			unix.BPF_JMP | unix.BPF_JGT | unix.BPF_K,
+			unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K:
+			var cond bool
+			switch insn.Code &^ (unix.BPF_JMP | unix.BPF_K) {
+			case unix.BPF_JEQ:
+				cond = acc == insn.K
+			case unix.BPF_JGT:
+				cond = acc > insn.K
+			case unix.BPF_JGE:
+				cond = acc >= insn.K
+			}
+			if cond {
+				pc += int(insn.Jt)
+			} else {
+				pc += int(insn.Jf)
+			}
+		default:
+			t.Fatalf("unexpected instruction: %+v", insn)
+		}
+	}
+
+	t.Fatal("program did not return")
+	return 0
+}
+
+func TestCompile(t *testing.T) {
+	filter, err := Compile(&runtimespec.LinuxSeccomp{
+		DefaultAction: runtimespec.ActErrno,
+		Architectures: []runtimespec.Arch{nativeArch},
+		Syscalls: []runtimespec.LinuxSyscall{
+			{
+				Names:  []string{"read", "write", "unknown_syscall"},
+				Action: runtimespec.ActAllow,
+			},
+			{
+				Names:  []string{"personality"},
+				Action: runtimespec.ActAllow,
+				Args: []runtimespec.LinuxSeccompArg{
+					{Index: 0, Value: 0xffffffff, Op: runtimespec.OpEqualTo},
+				},
+			},
+			{
+				Names:  []string{"clone"},
+				Action: runtimespec.ActAllow,
+				Args: []runtimespec.LinuxSeccompArg{
+					{Index: 0, Value: unix.CLONE_NEWNS | unix.CLONE_NEWUSER, ValueTwo: 0, Op: runtimespec.OpMaskedEqual},
+				},
+			},
+			{
+				Names:  []string{"mmap"},
+				Action: runtimespec.ActKill,
+				Args: []runtimespec.LinuxSeccompArg{
+					{Index: 1, Value: 1 << 32, Op: runtimespec.OpGreaterThan},
+				},
+			},
+			{
+				Names:  []string{"mmap"},
+				Action: runtimespec.ActAllow,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	allow := retAllow
+	deny := retErrno | uint32(unix.EPERM)
+	nr := func(name string) uint32 { return uint32(syscallNumbers[name]) }
+
+	for _, tc := range []struct {
+		name     string
+		arch     uint32
+		nr       uint32
+		args     [6]uint64
+		expected uint32
+	}{
+		{name: "allowed syscall", arch: auditArch, nr: nr("read"), expected: allow},
+		{name: "other allowed syscall", arch: auditArch, nr: nr("write"), expected: allow},
+		{name: "default action", arch: auditArch, nr: nr("mount"), expected: deny},
+		{name: "other architecture", arch: 0x40000003, nr: nr("read"), expected: retKillProcess},
+		{name: "arg equal", arch: auditArch, nr: nr("personality"), args: [6]uint64{0xffffffff}, expected: allow},
+		{name: "arg not equal", arch: auditArch, nr: nr("personality"), args: [6]uint64{0x1ffffffff}, expected: deny},
+		{name: "arg masked equal", arch: auditArch, nr: nr("clone"), args: [6]uint64{unix.CLONE_NEWNET}, expected: allow},
+		{name: "arg masked not equal", arch: auditArch, nr: nr("clone"), args: [6]uint64{unix.CLONE_NEWUSER}, expected: deny},
+		{name: "arg greater than", arch: auditArch, nr: nr("mmap"), args: [6]uint64{0, 1<<32 + 1}, expected: retKillThread},
+		{name: "arg not greater than", arch: auditArch, nr: nr("mmap"), args: [6]uint64{0, 1 << 32}, expected: allow},
+	} {
+		if got := run(t, filter.Program, tc.arch, tc.nr, tc.args); got != tc.expected {
+			t.Errorf("%s: expected: %#x, got: %#x", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestCompileOperators(t *testing.T) {
+	for _, tc := range []struct {
+		op       runtimespec.LinuxSeccompOperator
+		value    uint64
+		arg      uint64
+		expected bool
+	}{
+		{op: runtimespec.OpNotEqual, value: 5, arg: 5, expected: false},
+		{op: runtimespec.OpNotEqual, value: 5, arg: 1<<32 | 5, expected: true},
+		{op: runtimespec.OpLessThan, value: 1 << 32, arg: 1<<32 - 1, expected: true},
+		{op: runtimespec.OpLessThan, value: 1 << 32, arg: 1 << 32, expected: false},
+		{op: runtimespec.OpLessEqual, value: 1 << 32, arg: 1 << 32, expected: true},
+		{op: runtimespec.OpLessEqual, value: 1 << 32, arg: 1<<32 + 1, expected: false},
+		{op: runtimespec.OpGreaterEqual, value: 1 << 32, arg: 1 << 32, expected: true},
+		{op: runtimespec.OpGreaterEqual, value: 1 << 32, arg: 1<<32 - 1, expected: false},
+	} {
+		filter, err := Compile(&runtimespec.LinuxSeccomp{
+			DefaultAction: runtimespec.ActErrno,
+			Syscalls: []runtimespec.LinuxSyscall{
+				{
+					Names:  []string{"read"},
+					Action: runtimespec.ActAllow,
+					Args: []runtimespec.LinuxSeccompArg{
+						{Index: 2, Value: tc.value, Op: tc.op},
+					},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		got := run(t, filter.Program, auditArch, uint32(syscallNumbers["read"]), [6]uint64{0, 0, tc.arg}) == retAllow
+		if got != tc.expected {
+			t.Errorf("%s %#x %#x: expected: %t, got: %t", tc.op, tc.arg, tc.value, tc.expected, got)
+		}
+	}
+}
+
+func TestCompileErrnoRet(t *testing.T) {
+	enosys := uint(unix.ENOSYS)
+	eacces := uint(unix.EACCES)
+
+	filter, err := Compile(&runtimespec.LinuxSeccomp{
+		DefaultAction:   runtimespec.ActErrno,
+		DefaultErrnoRet: &eacces,
+		Syscalls: []runtimespec.LinuxSyscall{
+			{
+				Names:    []string{"clone3"},
+				Action:   runtimespec.ActErrno,
+				ErrnoRet: &enosys,
+			},
+			{
+				Names:  []string{"mount"},
+				Action: runtimespec.ActKillProcess,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	nr := func(name string) uint32 { return uint32(syscallNumbers[name]) }
+
+	for _, tc := range []struct {
+		name     string
+		nr       uint32
+		expected uint32
+	}{
+		{name: "errno ret", nr: nr("clone3"), expected: retErrno | uint32(unix.ENOSYS)},
+		{name: "default errno ret", nr: nr("read"), expected: retErrno | uint32(unix.EACCES)},
+		{name: "kill process", nr: nr("mount"), expected: retKillProcess},
+	} {
+		if got := run(t, filter.Program, auditArch, tc.nr, [6]uint64{}); got != tc.expected {
+			t.Errorf("%s: expected: %#x, got: %#x", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestSyscallNumbers(t *testing.T) {
+	for _, abi := range abis {
+		// `SYS_SYSCALL_MASK` is defined for arm but it is not a syscall.
+		if _, ok := abi.syscalls["syscall_mask"]; ok {
+			t.Errorf("%s: unexpected syscall 'syscall_mask'", abi.arch)
+		}
+
+		if len(abi.syscalls) == 0 {
+			t.Errorf("%s: expected syscalls", abi.arch)
+		}
+	}
+}
+
+func TestCompileArchitectures(t *testing.T) {
+	var archs []runtimespec.Arch
+	for _, abi := range abis {
+		archs = append(archs, abi.arch)
+	}
+
+	// Allowing all the syscalls makes the blocks of rules large enough to
+	// require long jumps.
+	var names []string
+	for name := range syscallNumbers {
+		if name != "mount" {
+			names = append(names, name)
+		}
+	}
+
+	config := &runtimespec.LinuxSeccomp{
+		DefaultAction: runtimespec.ActErrno,
+		Architectures: archs,
+		Syscalls: []runtimespec.LinuxSyscall{
+			{Names: names, Action: runtimespec.ActAllow},
+		},
+	}
+
+	filter, err := Compile(config)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	config.Architectures = []runtimespec.Arch{nativeArch}
+	nativeFilter, err := Compile(config)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	deny := retErrno | uint32(unix.EPERM)
+
+	for _, abi := range abis {
+		for _, tc := range []struct {
+			name     string
+			expected uint32
+		}{
+			{name: "read", expected: retAllow},
+			{name: "ioctl", expected: retAllow},
+			{name: "mount", expected: deny},
+		} {
+			nr := uint32(abi.syscalls[tc.name])
+			if got := run(t, filter.Program, abi.auditArch, nr, [6]uint64{}); got != tc.expected {
+				t.Errorf("%s %s: expected: %#x, got: %#x", abi.arch, tc.name, tc.expected, got)
+			}
+
+			expected := tc.expected
+			if abi.arch != nativeArch {
+				expected = retKillProcess
+			}
+			if got := run(t, nativeFilter.Program, abi.auditArch, nr, [6]uint64{}); got != expected {
+				t.Errorf("%s %s (native only): expected: %#x, got: %#x", abi.arch, tc.name, expected, got)
+			}
+		}
+	}
+}
+
+func TestCompileNil(t *testing.T) {
+	filter, err := Compile(nil)
+	if err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+
+	if filter != nil {
+		t.Errorf("expected no filter, got: %+v", filter)
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	for _, config := range []*runtimespec.LinuxSeccomp{
+		{DefaultAction: "SCMP_ACT_UNKNOWN"},
+		{DefaultAction: runtimespec.ActAllow, Flags: []runtimespec.LinuxSeccompFlag{"SECCOMP_FILTER_FLAG_UNKNOWN"}},
+		{
+			DefaultAction: runtimespec.ActAllow,
+			Syscalls: []runtimespec.LinuxSyscall{
+				{Names: []string{"read"}, Action: runtimespec.ActErrno, Args: []runtimespec.LinuxSeccompArg{{Index: 6}}},
+			},
+		},
+		{
+			DefaultAction: runtimespec.ActAllow,
+			Syscalls: []runtimespec.LinuxSyscall{
+				{Names: []string{"read"}, Action: runtimespec.ActErrno, Args: []runtimespec.LinuxSeccompArg{{Op: "SCMP_CMP_UNKNOWN"}}},
+			},
+		},
+		{
+			DefaultAction: runtimespec.ActAllow,
+			Syscalls: []runtimespec.LinuxSyscall{
+				{Names: []string{"read"}, Action: runtimespec.ActKill, ErrnoRet: new(uint)},
+			},
+		},
+		{
+			DefaultAction: runtimespec.ActAllow,
+			Syscalls: []runtimespec.LinuxSyscall{
+				{Names: []string{"read"}, Action: runtimespec.ActNotify},
+			},
+		},
+		{DefaultAction: runtimespec.ActAllow, ListenerPath: "/run/seccomp.sock"},
+	} {
+		if _, err := Compile(config); err == nil {
+			t.Errorf("expected an error for: %+v", config)
+		}
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	if nativeArch == "" {
+		t.Skip("seccomp is not supported on this architecture")
+	}
+
+	f := Features()
+
+	if f.Enabled == nil || !*f.Enabled {
+		t.Errorf("expected seccomp to be enabled")
+	}
+
+	contains := func(values []string, value string) bool {
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !contains(f.Actions, string(runtimespec.ActErrno)) {
+		t.Errorf("expected actions to contain %s, got: %v", runtimespec.ActErrno, f.Actions)
+	}
+
+	if contains(f.Actions, string(runtimespec.ActNotify)) {
+		t.Errorf("expected actions to not contain %s, got: %v", runtimespec.ActNotify, f.Actions)
+	}
+
+	if len(f.Operators) != len(specOperators) {
+		t.Errorf("expected %d operators, got: %v", len(specOperators), f.Operators)
+	}
+
+	if !contains(f.SupportedFlags, "SECCOMP_FILTER_FLAG_LOG") {
+		t.Errorf("expected flags to contain SECCOMP_FILTER_FLAG_LOG, got: %v", f.SupportedFlags)
+	}
+}
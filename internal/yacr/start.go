@@ -1,10 +1,7 @@
 package yacr
 
 import (
-	"bytes"
-	"errors"
 	"fmt"
-	"io/ioutil"
 	"net"
 
 	"github.com/sirupsen/logrus"
@@ -35,34 +32,29 @@ func Start(rootDir, containerId string) error {
 	}
 	defer conn.Close()
 
-	// Hooks to be run before the container process is executed.
-	// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/config.md#prestart
-	if err := container.ExecuteHooks("Prestart"); err != nil {
+	// The state is passed to the `startContainer` hooks by the container
+	// process.
+	if err := ipc.SendPayload(conn, ipc.START_CONTAINER, container.State); err != nil {
 		return err
 	}
 
-	if err := ipc.SendMessage(conn, ipc.START_CONTAINER); err != nil {
-		return err
+	// The container process should send a "OK" right before it calls exec(3) OR
+	// an error if something went wrong.
+	if err := ipc.AwaitMessage(conn, ipc.OK); err != nil {
+		return fmt.Errorf("failed to start container: %w", err)
 	}
 
-	container.UpdateStatus(constants.StateRunning)
-
-	// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/config.md#poststart
-	if err := container.ExecuteHooks("Poststart"); err != nil {
+	if err := container.UpdateStatus(constants.StateCreated, constants.StateRunning); err != nil {
 		return err
 	}
 
-	// The container process should send a "OK" right before it calls exec(3) OR
-	// an error if something went wrong.
-	msg, err := ioutil.ReadAll(conn)
-	if err != nil {
+	// A failing `poststart` hook is only logged as a warning because the
+	// container is already running (see `ExecuteHooks()`).
+	// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/config.md#poststart
+	if err := container.ExecuteHooks("poststart"); err != nil {
 		return err
 	}
 
-	if !bytes.Equal(msg, []byte(ipc.OK)) {
-		return errors.New(string(msg))
-	}
-
 	logrus.WithFields(logrus.Fields{
 		"id": container.ID(),
 	}).Info("start: ok")
@@ -4,16 +4,30 @@ import (
 	"encoding/json"
 	"io"
 
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/willdurand/containers/internal/yacr/container"
 )
 
+// containerState is the output of `yacr state`. In addition to the OCI state,
+// it contains the current resources of the container, which can be changed
+// with `yacr update`.
+type containerState struct {
+	runtimespec.State
+	Resources *runtimespec.LinuxResources `json:"resources,omitempty"`
+}
+
 func State(rootDir, containerId string, w io.Writer) error {
 	container, err := container.LoadWithBundleConfig(rootDir, containerId)
 	if err != nil {
 		return err
 	}
 
-	if err := json.NewEncoder(w).Encode(container.State); err != nil {
+	state := containerState{State: container.State}
+	if container.Spec.Linux != nil {
+		state.Resources = container.Spec.Linux.Resources
+	}
+
+	if err := json.NewEncoder(w).Encode(state); err != nil {
 		return err
 	}
 
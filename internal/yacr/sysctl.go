@@ -0,0 +1,101 @@
+package yacr
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// ipcSysctls contains the prefixes of the IPC namespace sysctls (in addition
+// to `fs.mqueue.*`).
+var ipcSysctls = []string{"kernel.sem", "kernel.shm", "kernel.msg"}
+
+// validateSysctl checks that the kernel parameters of a container are
+// namespaced, i.e. that the container does not change the parameters of the
+// host.
+//
+// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/config-linux.md#sysctl
+func validateSysctl(sysctl map[string]string, namespaces []runtimespec.LinuxNamespace) error {
+	hasNamespace := make(map[runtimespec.LinuxNamespaceType]bool)
+	for _, ns := range namespaces {
+		hasNamespace[ns.Type] = true
+	}
+
+	for key := range sysctl {
+		if _, err := sysctlPath(key); err != nil {
+			return err
+		}
+
+		nsType, err := sysctlNamespace(key)
+		if err != nil {
+			return err
+		}
+
+		if !hasNamespace[nsType] {
+			return fmt.Errorf("sysctl '%s' requires a %s namespace", key, nsType)
+		}
+	}
+
+	return nil
+}
+
+// sysctlNamespace returns the type of the namespace a kernel parameter belongs
+// to, or an error when the parameter is not namespaced.
+func sysctlNamespace(key string) (runtimespec.LinuxNamespaceType, error) {
+	// Keys can use slashes as separators, e.g. when a network interface name
+	// contains dots.
+	name := strings.ReplaceAll(key, "/", ".")
+
+	switch {
+	case strings.HasPrefix(name, "net."):
+		return runtimespec.NetworkNamespace, nil
+
+	case strings.HasPrefix(name, "fs.mqueue."):
+		return runtimespec.IPCNamespace, nil
+
+	case name == "kernel.hostname" || name == "kernel.domainname":
+		return runtimespec.UTSNamespace, nil
+	}
+
+	for _, prefix := range ipcSysctls {
+		if strings.HasPrefix(name, prefix) {
+			return runtimespec.IPCNamespace, nil
+		}
+	}
+
+	return "", fmt.Errorf("sysctl '%s' is not namespaced and cannot be set", key)
+}
+
+// sysctlPath returns the path of a kernel parameter relative to `/proc/sys`.
+func sysctlPath(key string) (string, error) {
+	path := key
+	if !strings.Contains(key, "/") {
+		path = strings.ReplaceAll(key, ".", "/")
+	}
+
+	if path == "" || filepath.Clean("/"+path) != "/"+path {
+		return "", fmt.Errorf("invalid sysctl '%s'", key)
+	}
+
+	return path, nil
+}
+
+// setSysctl writes the kernel parameters of a container to `/proc/sys`. It
+// must be called once the container's `/proc` has been mounted.
+func setSysctl(sysctl map[string]string) error {
+	for key, value := range sysctl {
+		path, err := sysctlPath(key)
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(filepath.Join("/proc/sys", path), []byte(value), 0); err != nil {
+			return fmt.Errorf("failed to set sysctl '%s': %w", key, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package yacr
+
+import (
+	"testing"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestValidateSysctl(t *testing.T) {
+	err := validateSysctl(map[string]string{
+		"net.ipv4.ip_unprivileged_port_start": "0",
+		"net/ipv4/conf/eth0.100/forwarding":   "1",
+		"kernel.shmmax":                       "1024",
+		"kernel.msgmax":                       "1024",
+		"kernel.sem":                          "250 32000 100 128",
+		"fs.mqueue.queues_max":                "128",
+		"kernel.hostname":                     "test",
+	}, []runtimespec.LinuxNamespace{
+		{Type: runtimespec.NetworkNamespace},
+		{Type: runtimespec.IPCNamespace, Path: "/proc/123/ns/ipc"},
+		{Type: runtimespec.UTSNamespace},
+	})
+	if err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+}
+
+func TestValidateSysctlInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		key        string
+		namespaces []runtimespec.LinuxNamespace
+	}{
+		{"kernel.pid_max", []runtimespec.LinuxNamespace{{Type: runtimespec.PIDNamespace}}},
+		{"vm.overcommit_memory", nil},
+		{"net.core.somaxconn", []runtimespec.LinuxNamespace{{Type: runtimespec.IPCNamespace}}},
+		{"kernel.shmmax", []runtimespec.LinuxNamespace{{Type: runtimespec.NetworkNamespace}}},
+		{"kernel.hostname", nil},
+		{"net/../../kernel/pid_max", []runtimespec.LinuxNamespace{{Type: runtimespec.NetworkNamespace}}},
+	} {
+		sysctl := map[string]string{tc.key: "1"}
+		if err := validateSysctl(sysctl, tc.namespaces); err == nil {
+			t.Errorf("expected an error for: %s", tc.key)
+		}
+	}
+}
+
+func TestSysctlPath(t *testing.T) {
+	for key, expected := range map[string]string{
+		"net.core.somaxconn":                "net/core/somaxconn",
+		"net/ipv4/conf/eth0.100/forwarding": "net/ipv4/conf/eth0.100/forwarding",
+	} {
+		path, err := sysctlPath(key)
+		if err != nil {
+			t.Errorf("expected no error, got: %v", err)
+		}
+		if path != expected {
+			t.Errorf("expected: %s, got: %s", expected, path)
+		}
+	}
+}
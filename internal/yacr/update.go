@@ -0,0 +1,192 @@
+package yacr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/sirupsen/logrus"
+	"github.com/willdurand/containers/internal/yacr/container"
+)
+
+type UpdateOpts struct {
+	ID string
+	// ResourcesFile is the path to a JSON file that contains the new resources
+	// ("-" for the standard input).
+	ResourcesFile string
+	// Resources contains the resources passed with flags, which take precedence
+	// over the ones in `ResourcesFile`.
+	Resources runtimespec.LinuxResources
+}
+
+// Update changes the resource limits of a container. The new values are
+// merged into the current resources, applied to the cgroup of the container
+// and then persisted so that the other commands use them. A limit is removed
+// with -1 (e.g. `memory.max` is set to "max").
+//
+// The cgroup interface files are written one at a time so, when the new values
+// cannot be applied or persisted, the previous values of these files are
+// restored (on a best effort basis) and nothing is persisted.
+func Update(rootDir string, opts UpdateOpts) error {
+	container, err := container.LoadWithBundleConfig(rootDir, opts.ID)
+	if err != nil {
+		return err
+	}
+
+	if container.IsStopped() {
+		return fmt.Errorf("unexpected status '%s' for container '%s'", container.State.Status, container.ID())
+	}
+
+	cgroup, err := container.Cgroup()
+	if err != nil {
+		return err
+	}
+
+	if !cgroup.Exists() {
+		return fmt.Errorf("container '%s' does not have a cgroup", container.ID())
+	}
+
+	var updates [][]byte
+	if opts.ResourcesFile != "" {
+		data, err := readResourcesFile(opts.ResourcesFile)
+		if err != nil {
+			return err
+		}
+		updates = append(updates, data)
+	}
+
+	data, err := json.Marshal(opts.Resources)
+	if err != nil {
+		return err
+	}
+	updates = append(updates, data)
+
+	// Concurrent updates must not overwrite each other so the resources are
+	// loaded again while holding the lock.
+	unlock, err := container.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// The container might have been stopped in the meantime.
+	if err := container.ReloadState(); err != nil {
+		return err
+	}
+	if container.IsStopped() {
+		return fmt.Errorf("unexpected status '%s' for container '%s'", container.State.Status, container.ID())
+	}
+
+	if err := container.LoadResources(); err != nil {
+		return err
+	}
+
+	// The new values are merged into the resources of the spec so that the
+	// device rules are computed with them.
+	if container.Spec.Linux == nil {
+		container.Spec.Linux = new(runtimespec.Linux)
+	}
+	if container.Spec.Linux.Resources == nil {
+		container.Spec.Linux.Resources = new(runtimespec.LinuxResources)
+	}
+	resources := container.Spec.Linux.Resources
+
+	// A copy of the current resources is needed to restore the device rules
+	// because merging the new values changes the resources in place.
+	data, err = json.Marshal(resources)
+	if err != nil {
+		return err
+	}
+	previous := new(runtimespec.LinuxResources)
+	if _, err := mergeResources(previous, data); err != nil {
+		return err
+	}
+
+	updateDevices, err := mergeResources(resources, updates...)
+	if err != nil {
+		return err
+	}
+
+	// Like in `setupCgroup()`, device rules cannot be applied in rootless mode.
+	if updateDevices && isRootless(container.Spec) {
+		return fmt.Errorf("cannot update the device rules of container '%s' in rootless mode", container.ID())
+	}
+
+	snapshot, err := cgroup.Snapshot(resources)
+	if err != nil {
+		return fmt.Errorf("failed to update container '%s': %w", container.ID(), err)
+	}
+
+	err = cgroup.Apply(resources)
+	// Attaching a new device filter replaces the previous one.
+	if err == nil && updateDevices {
+		err = cgroup.ApplyDevices(deviceCgroupRules(container.Spec))
+	}
+	if err == nil {
+		err = container.SaveResources(resources)
+	}
+	if err != nil {
+		if err := cgroup.Restore(snapshot); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"id":    container.ID(),
+				"error": err,
+			}).Warn("failed to restore cgroup settings")
+		}
+
+		if updateDevices {
+			container.Spec.Linux.Resources = previous
+			if err := cgroup.ApplyDevices(deviceCgroupRules(container.Spec)); err != nil {
+				logrus.WithFields(logrus.Fields{
+					"id":    container.ID(),
+					"error": err,
+				}).Warn("failed to restore device rules")
+			}
+		}
+
+		return fmt.Errorf("failed to update container '%s': %w", container.ID(), err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":        container.ID(),
+		"resources": resources,
+	}).Info("update: ok")
+
+	return nil
+}
+
+// mergeResources merges JSON documents into the given resources. It returns
+// `true` when the device rules have been changed.
+func mergeResources(resources *runtimespec.LinuxResources, updates ...[]byte) (bool, error) {
+	updateDevices := false
+
+	for _, data := range updates {
+		// Decoding JSON into an existing value only changes the fields that are
+		// present in the JSON document, which is how the resources are merged.
+		if err := json.Unmarshal(data, resources); err != nil {
+			return false, fmt.Errorf("failed to parse resources: %w", err)
+		}
+
+		update := new(runtimespec.LinuxResources)
+		if err := json.Unmarshal(data, update); err != nil {
+			return false, fmt.Errorf("failed to parse resources: %w", err)
+		}
+		updateDevices = updateDevices || update.Devices != nil
+	}
+
+	return updateDevices, nil
+}
+
+func readResourcesFile(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resources: %w", err)
+	}
+
+	return data, nil
+}
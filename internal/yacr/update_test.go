@@ -0,0 +1,67 @@
+package yacr
+
+import (
+	"testing"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestMergeResources(t *testing.T) {
+	limit := int64(1024)
+	quota := int64(50000)
+	resources := &runtimespec.LinuxResources{
+		Memory: &runtimespec.LinuxMemory{Limit: &limit},
+		CPU:    &runtimespec.LinuxCPU{Quota: &quota, Cpus: "0-1"},
+		Pids:   &runtimespec.LinuxPids{Limit: 10},
+	}
+
+	devices, err := mergeResources(
+		resources,
+		[]byte(`{"memory":{"reservation":512},"unified":{"memory.high":"2048"}}`),
+		[]byte(`{"cpu":{"quota":100000},"pids":{"limit":20}}`),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if devices {
+		t.Errorf("expected devices to be unchanged")
+	}
+
+	if *resources.Memory.Limit != 1024 {
+		t.Errorf("expected memory limit: 1024, got: %d", *resources.Memory.Limit)
+	}
+
+	if resources.Memory.Reservation == nil || *resources.Memory.Reservation != 512 {
+		t.Errorf("expected memory reservation: 512, got: %v", resources.Memory.Reservation)
+	}
+
+	if *resources.CPU.Quota != 100000 {
+		t.Errorf("expected CPU quota: 100000, got: %d", *resources.CPU.Quota)
+	}
+
+	if resources.CPU.Cpus != "0-1" {
+		t.Errorf("expected cpus: 0-1, got: %s", resources.CPU.Cpus)
+	}
+
+	if resources.Pids.Limit != 20 {
+		t.Errorf("expected pids limit: 20, got: %d", resources.Pids.Limit)
+	}
+
+	if resources.Unified["memory.high"] != "2048" {
+		t.Errorf("expected memory.high: 2048, got: %s", resources.Unified["memory.high"])
+	}
+
+	devices, err = mergeResources(resources, []byte(`{"devices":[{"allow":false,"access":"rwm"}]}`))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if !devices {
+		t.Errorf("expected devices to be changed")
+	}
+
+	if _, err := mergeResources(resources, []byte(`{"memory":`)); err == nil {
+		t.Errorf("expected an error")
+	}
+}
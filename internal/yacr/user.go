@@ -0,0 +1,66 @@
+package yacr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+// setupUser changes the user and groups of the current thread. In a user
+// namespace, the IDs must be mapped, otherwise the kernel returns `EINVAL`,
+// which we convert into a more meaningful error.
+//
+// See: https://github.com/opencontainers/runtime-spec/blob/27924127bf391ea7691924c6dcb01f3369d69fe2/config.md#user
+func setupUser(user runtimespec.User) error {
+	var gids []int
+	for _, gid := range user.AdditionalGids {
+		gids = append(gids, int(gid))
+	}
+
+	if setgroupsAllowed() {
+		if err := unix.Setgroups(gids); err != nil {
+			if errors.Is(err, unix.EINVAL) {
+				return fmt.Errorf("additional gids %v cannot be mapped in the user namespace of the container", user.AdditionalGids)
+			}
+			return fmt.Errorf("failed to set additional gids: %w", err)
+		}
+	} else if len(gids) > 0 {
+		return errors.New("additional gids cannot be set because setgroups(2) is denied in the user namespace of the container")
+	}
+
+	if err := unix.Setresgid(int(user.GID), int(user.GID), int(user.GID)); err != nil {
+		if errors.Is(err, unix.EINVAL) {
+			return fmt.Errorf("gid %d cannot be mapped in the user namespace of the container", user.GID)
+		}
+		return fmt.Errorf("failed to set gid: %w", err)
+	}
+
+	if err := unix.Setresuid(int(user.UID), int(user.UID), int(user.UID)); err != nil {
+		if errors.Is(err, unix.EINVAL) {
+			return fmt.Errorf("uid %d cannot be mapped in the user namespace of the container", user.UID)
+		}
+		return fmt.Errorf("failed to set uid: %w", err)
+	}
+
+	// The umask should not be changed when it has not been specified.
+	if user.Umask != nil {
+		unix.Umask(int(*user.Umask))
+	}
+
+	return nil
+}
+
+// setgroupsAllowed returns `false` when setgroups(2) has been disabled in the
+// user namespace of the current process, and `true` otherwise.
+func setgroupsAllowed() bool {
+	data, err := os.ReadFile("/proc/self/setgroups")
+	if err != nil {
+		return true
+	}
+
+	return !bytes.Equal(bytes.TrimSpace(data), []byte("deny"))
+}
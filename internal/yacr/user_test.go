@@ -0,0 +1,68 @@
+package yacr
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+func TestSetupUser(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("test requires root")
+	}
+
+	// setupUser changes the credentials of the process so it is executed in a
+	// child process (see `TestSetupUserHelper`).
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSetupUserHelper$")
+	cmd.Env = append(os.Environ(), "YACR_TEST_SETUP_USER=1")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v (output: %s)", err, out)
+	}
+
+	expected := "uid=1000 gid=1001 groups=[2000 2001] umask=27"
+	if !strings.Contains(string(out), expected) {
+		t.Errorf("expected %q, got: %s", expected, out)
+	}
+}
+
+func TestSetupUserHelper(t *testing.T) {
+	if os.Getenv("YACR_TEST_SETUP_USER") != "1" {
+		t.Skip("helper for TestSetupUser")
+	}
+
+	umask := uint32(0o027)
+	err := setupUser(runtimespec.User{
+		UID:            1000,
+		GID:            1001,
+		AdditionalGids: []uint32{2000, 2001},
+		Umask:          &umask,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	groups, err := unix.Getgroups()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	fmt.Printf("uid=%d gid=%d groups=%v umask=%o\n", unix.Getuid(), unix.Getgid(), groups, unix.Umask(0))
+}
+
+func TestSetgroupsAllowed(t *testing.T) {
+	data, err := os.ReadFile("/proc/self/setgroups")
+	if err != nil {
+		t.Skip("setgroups file is not available")
+	}
+
+	expected := strings.TrimSpace(string(data)) != "deny"
+	if allowed := setgroupsAllowed(); allowed != expected {
+		t.Errorf("expected %t, got: %t", expected, allowed)
+	}
+}
@@ -0,0 +1,142 @@
+package yacr
+
+import (
+	"errors"
+	"fmt"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/willdurand/containers/internal/yacr/cgroups"
+	"github.com/willdurand/containers/internal/yacr/seccomp"
+	"golang.org/x/sys/unix"
+)
+
+// validateSpec checks the runtime configuration of a container before the
+// container process is created so that `yacr create` can report invalid
+// configurations early.
+func validateSpec(spec runtimespec.Spec) error {
+	if spec.Process == nil {
+		return errors.New("no process configuration found")
+	}
+
+	if spec.Linux == nil {
+		return errors.New("no linux configuration found")
+	}
+
+	if err := validateProcess(spec.Process); err != nil {
+		return err
+	}
+
+	if err := validateNamespaces(spec.Linux.Namespaces); err != nil {
+		return err
+	}
+
+	if err := validateTimeOffsets(spec.Linux.TimeOffsets, spec.Linux.Namespaces); err != nil {
+		return err
+	}
+
+	if spec.Domainname != "" && !hasNamespace(spec.Linux.Namespaces, runtimespec.UTSNamespace) {
+		return errors.New("domainname requires a UTS namespace")
+	}
+
+	if spec.Hooks != nil {
+		for _, hooks := range [][]runtimespec.Hook{
+			spec.Hooks.Prestart,
+			spec.Hooks.CreateRuntime,
+			spec.Hooks.CreateContainer,
+			spec.Hooks.StartContainer,
+			spec.Hooks.Poststart,
+			spec.Hooks.Poststop,
+		} {
+			for _, hook := range hooks {
+				if hook.Timeout != nil && *hook.Timeout <= 0 {
+					return fmt.Errorf("invalid timeout %d for hook '%s'", *hook.Timeout, hook.Path)
+				}
+			}
+		}
+	}
+
+	for _, m := range spec.Mounts {
+		if len(m.UIDMappings) > 0 || len(m.GIDMappings) > 0 {
+			return fmt.Errorf("idmapped mounts are not supported (mount '%s')", m.Destination)
+		}
+	}
+
+	if spec.Linux.Personality != nil {
+		return errors.New("personality is not supported")
+	}
+
+	if spec.Linux.IntelRdt != nil {
+		return errors.New("intelRdt is not supported")
+	}
+
+	if spec.Linux.MountLabel != "" {
+		return errors.New("mountLabel is not supported")
+	}
+
+	if p := spec.Linux.RootfsPropagation; p != "" {
+		flag, ok := propagationFlags[p]
+		if !ok {
+			return fmt.Errorf("invalid rootfs propagation '%s'", p)
+		}
+		// `pivot_root` does not work when the root filesystem is shared.
+		if flag&unix.MS_SHARED != 0 {
+			return fmt.Errorf("rootfs propagation '%s' is not supported", p)
+		}
+	}
+
+	if _, err := cgroups.ConvertResources(spec.Linux.Resources); err != nil {
+		return err
+	}
+
+	if err := validateSysctl(spec.Linux.Sysctl, spec.Linux.Namespaces); err != nil {
+		return err
+	}
+
+	if err := validateDevices(spec.Linux.Devices); err != nil {
+		return err
+	}
+
+	if _, err := seccomp.Compile(spec.Linux.Seccomp); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateProcess checks the configuration of a process, which is either the
+// container process or a process executed with `yacr exec`.
+func validateProcess(process *runtimespec.Process) error {
+	if _, err := newCapabilities(process.Capabilities); err != nil {
+		return err
+	}
+
+	if err := validateRlimits(process.Rlimits); err != nil {
+		return err
+	}
+
+	if adj := process.OOMScoreAdj; adj != nil && (*adj < -1000 || *adj > 1000) {
+		return fmt.Errorf("invalid oom score adj %d", *adj)
+	}
+
+	if err := validateScheduler(process.Scheduler); err != nil {
+		return err
+	}
+
+	if err := validateIOPriority(process.IOPriority); err != nil {
+		return err
+	}
+
+	if process.ExecCPUAffinity != nil {
+		return errors.New("execCPUAffinity is not supported")
+	}
+
+	if process.ApparmorProfile != "" {
+		return errors.New("apparmorProfile is not supported")
+	}
+
+	if process.SelinuxLabel != "" {
+		return errors.New("selinuxLabel is not supported")
+	}
+
+	return nil
+}
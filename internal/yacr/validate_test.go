@@ -0,0 +1,52 @@
+package yacr
+
+import (
+	"testing"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestValidateSpec(t *testing.T) {
+	for _, propagation := range []string{"", "private", "rslave", "runbindable"} {
+		spec := runtimespec.Spec{
+			Process: &runtimespec.Process{},
+			Linux:   &runtimespec.Linux{RootfsPropagation: propagation},
+		}
+
+		if err := validateSpec(spec); err != nil {
+			t.Errorf("%s: expected no error, got: %v", propagation, err)
+		}
+	}
+}
+
+func TestValidateSpecInvalid(t *testing.T) {
+	swappiness := uint64(60)
+
+	for _, linux := range []*runtimespec.Linux{
+		{RootfsPropagation: "shared"},
+		{RootfsPropagation: "rshared"},
+		{RootfsPropagation: "unknown"},
+		{Resources: &runtimespec.LinuxResources{Memory: &runtimespec.LinuxMemory{Swappiness: &swappiness}}},
+		{MountLabel: "system_u:object_r:container_file_t:s0"},
+	} {
+		spec := runtimespec.Spec{
+			Process: &runtimespec.Process{},
+			Linux:   linux,
+		}
+
+		if err := validateSpec(spec); err == nil {
+			t.Errorf("expected an error for: %+v", linux)
+		}
+	}
+}
+
+func TestValidateProcessUnsupported(t *testing.T) {
+	for _, process := range []*runtimespec.Process{
+		{ApparmorProfile: "docker-default"},
+		{SelinuxLabel: "system_u:system_r:container_t:s0"},
+	} {
+		if err := validateProcess(process); err == nil {
+			t.Errorf("expected an error for: %+v", process)
+		}
+	}
+}
@@ -63,7 +63,7 @@ func NewShimFromFlags(flags *pflag.FlagSet) (*Yacs, error) {
 	containerLogFile, _ := flags.GetString("container-log-file")
 	exitCommand, _ := flags.GetString("exit-command")
 	exitCommandArgs, _ := flags.GetStringArray("exit-command-arg")
-	runtime, _ := flags.GetString("runtime")
+	runtimeName, _ := flags.GetString("runtime")
 	stdioDir, _ := flags.GetString("stdio-dir")
 
 	rootDir, _ := flags.GetString("root")
@@ -79,9 +79,19 @@ func NewShimFromFlags(flags *pflag.FlagSet) (*Yacs, error) {
 		stdioDir = baseDir
 	}
 
-	runtimePath, err := exec.LookPath(runtime)
+	runtimePath, err := exec.LookPath(runtimeName)
 	if err != nil {
-		return nil, fmt.Errorf("runtime '%s' not found", runtime)
+		return nil, fmt.Errorf("runtime '%s' not found", runtimeName)
+	}
+
+	// Reject the options that are not supported by the runtime before creating
+	// the container.
+	features, err := runtime.QueryFeatures(runtimePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := runtime.CheckFeatures(&spec, features); err != nil {
+		return nil, err
 	}
 
 	if containerLogFile == "" {
@@ -100,7 +110,7 @@ func NewShimFromFlags(flags *pflag.FlagSet) (*Yacs, error) {
 		exitCommand:          exitCommand,
 		exitCommandArgs:      exitCommandArgs,
 		httpServerReady:      make(chan error),
-		runtime:              runtime,
+		runtime:              runtimeName,
 		runtimePath:          runtimePath,
 		stdioDir:             stdioDir,
 	}, nil
@@ -12,6 +12,7 @@ import (
 
 	"github.com/google/uuid"
 	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/opencontainers/runtime-spec/specs-go/features"
 	"github.com/sirupsen/logrus"
 	"github.com/willdurand/containers/internal/cmd"
 	"github.com/willdurand/containers/internal/runtime"
@@ -28,6 +29,7 @@ type ContainerOpts struct {
 	Tty         bool
 	Detach      bool
 	PublishAll  bool
+	ReadOnly    bool
 }
 
 type Container struct {
@@ -99,8 +101,9 @@ func (c *Container) Command() []string {
 }
 
 // Mount creates a bundle configuration for the container and mounts its root
-// filesystem.
-func (c *Container) Mount() error {
+// filesystem. The features of the OCI runtime (if known) are used to leave out
+// the defaults that the runtime cannot honour.
+func (c *Container) Mount(f *features.Features) error {
 	for _, dir := range []string{
 		c.BaseDir,
 		c.datadir(),
@@ -156,6 +159,12 @@ func (c *Container) Mount() error {
 	if err != nil {
 		return err
 	}
+	if runtime.SupportsSeccomp(f) {
+		c.Config.Linux.Seccomp = defaultSeccompProfile()
+	} else {
+		logrus.WithField("id", c.ID).Warn("runtime does not support seccomp, the default seccomp profile is not applied")
+	}
+	c.Config.Root.Readonly = c.Opts.ReadOnly
 
 	c.Config.Process = &runtimespec.Process{
 		Terminal: c.Opts.Tty,
@@ -72,7 +72,7 @@ func ListContainers(rootDir string, all bool) (ContainerList, error) {
 			continue
 		}
 
-		status := state.State.Status
+		status := string(state.State.Status)
 		if state.Status.Exited() {
 			status = fmt.Sprintf(
 				"Exited (%d) %s ago",
@@ -23,10 +23,12 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/opencontainers/runtime-spec/specs-go/features"
 	"github.com/sirupsen/logrus"
 	"github.com/willdurand/containers/internal/cli"
 	"github.com/willdurand/containers/internal/constants"
 	"github.com/willdurand/containers/internal/logs"
+	"github.com/willdurand/containers/internal/runtime"
 	"github.com/willdurand/containers/internal/yacs"
 	"github.com/willdurand/containers/internal/yaman/container"
 	"golang.org/x/term"
@@ -113,10 +115,19 @@ func (s *Shim) Create(rootDir string) error {
 		}
 	}()
 
-	if err := s.Container.Mount(); err != nil {
+	f, err := s.queryRuntimeFeatures()
+	if err != nil {
+		return err
+	}
+
+	if err := s.Container.Mount(f); err != nil {
 		return err
 	}
 
+	if err := runtime.CheckFeatures(s.Container.Config, f); err != nil {
+		return fmt.Errorf("%s: %w", s.Opts.Runtime, err)
+	}
+
 	// Look up the path to the `yacs` shim binary.
 	yacs, err := exec.LookPath("yacs")
 	if err != nil {
@@ -188,6 +199,23 @@ func (s *Shim) Create(rootDir string) error {
 	return s.save()
 }
 
+// queryRuntimeFeatures returns the features of the selected OCI runtime so
+// that the container configuration can be checked early, i.e. before `yacs` is
+// started.
+func (s *Shim) queryRuntimeFeatures() (*features.Features, error) {
+	runtimePath, err := exec.LookPath(s.Opts.Runtime)
+	if err != nil {
+		return nil, fmt.Errorf("runtime '%s' not found", s.Opts.Runtime)
+	}
+
+	f, err := runtime.QueryFeatures(runtimePath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.Opts.Runtime, err)
+	}
+
+	return f, nil
+}
+
 // GetState queries the shim to retrieve its state and returns it.
 func (s *Shim) GetState() (*yacs.YacsState, error) {
 	// When a shim is terminated, the `State` property should be non-nil and
@@ -260,7 +288,7 @@ func (s *Shim) Delete() error {
 	}
 
 	switch state.State.Status {
-	case constants.StateRunning:
+	case constants.StateRunning, constants.StatePaused:
 		return fmt.Errorf("container '%s' is %s", s.Container.ID, state.State.Status)
 	case constants.StateStopped:
 		break
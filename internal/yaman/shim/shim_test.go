@@ -0,0 +1,24 @@
+package shim
+
+import (
+	"testing"
+
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/willdurand/containers/internal/constants"
+	"github.com/willdurand/containers/internal/yacs"
+	"github.com/willdurand/containers/internal/yaman/container"
+)
+
+func TestDeleteNotStopped(t *testing.T) {
+	for _, status := range []runtimespec.ContainerState{constants.StateRunning, constants.StatePaused} {
+		shim := &Shim{
+			Container: &container.Container{ID: "test-id"},
+			State:     &yacs.YacsState{State: runtimespec.State{Status: status}},
+		}
+
+		err := shim.Delete()
+		if err == nil || err.Error() != "container 'test-id' is "+string(status) {
+			t.Errorf("expected an error for status %s, got: %v", status, err)
+		}
+	}
+}